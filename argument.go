@@ -3,7 +3,9 @@ package marker
 import (
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 type Argument struct {
@@ -13,9 +15,68 @@ type Argument struct {
 	Required       bool
 	SyntaxFree     bool
 	UseValueSyntax bool
+	// Raw indicates that the whole text after the marker name is captured into the field
+	// verbatim, such as the shell command in '+generate:command go run ./gen, -v'.
+	Raw bool
+	// MinItems is the minimum number of items of a slice or map argument, zero means no limit.
+	MinItems int
+	// MaxItems is the maximum number of items of a slice or map argument, zero means no limit.
+	MaxItems int
+	// TargetName indicates that the field is not an argument, and it is filled with
+	// the name of the type, field or function which the marker is associated with.
+	TargetName bool
+	// RequiredIf is the condition making the argument required, the argument is optional
+	// if the condition is not satisfied.
+	RequiredIf *Condition
+	// Min is the lower bound of an integer or float argument such as 'min=-273.15'.
+	Min *Bound
+	// Max is the upper bound of an integer or float argument such as 'maxExclusive=100'.
+	Max *Bound
+	// Rest indicates that the field is not an argument, and it is filled with the unknown
+	// arguments of the marker. It can be only map[string]interface{}.
+	Rest bool
+	// AllowEmpty indicates that the slice or map argument must be given, but it can be
+	// an empty collection such as 'tags={}', which is set to an empty non-nil value.
+	AllowEmpty bool
+	// Deprecated is the deprecation message of the argument such as 'use title', a warning
+	// is reported if the argument is given. A deprecated argument is optional.
+	Deprecated string
+	// Replacement is the name of the argument which replaces the deprecated argument if
+	// the deprecation message is 'use <argument>'. The value of the deprecated argument
+	// is set to the replacement argument unless the replacement argument is given.
+	Replacement string
+	// Reference indicates that the value of the argument can be a reference to another
+	// marker on the same node such as '@limit' or '@limit.max'. The values starting with
+	// '@' are parsed as the literals such as '@daily' for the other arguments.
+	Reference bool
+}
+
+// Condition is a comparison of the value of an argument such as 'auth==token'.
+type Condition struct {
+	// Argument is the name of the compared argument.
+	Argument string
+	// Value is the text which the value of the argument is compared with.
+	Value string
+}
+
+// String returns the condition in the form of argument==value.
+func (condition Condition) String() string {
+	return condition.Argument + "==" + condition.Value
+}
+
+// Bound is a numeric limit of an argument, the limit itself is allowed unless it is exclusive.
+type Bound struct {
+	Value     float64
+	Exclusive bool
 }
 
 func ExtractArgument(structField reflect.StructField) (Argument, error) {
+	return extractArgument(structField, nil)
+}
+
+// extractArgument extracts the argument of the given struct field, the visiting
+// types are the nested struct types being extracted to detect recursive types.
+func extractArgument(structField reflect.StructField, visiting map[reflect.Type]bool) (Argument, error) {
 	fieldName := LowerCamelCase(structField.Name)
 
 	markerTag, tagExists := structField.Tag.Lookup("marker")
@@ -28,6 +89,18 @@ func ExtractArgument(structField reflect.StructField) (Argument, error) {
 	optionalOption := false
 	syntaxFree := false
 	useValueSyntax := false
+	raw := false
+	minItems := 0
+	maxItems := 0
+	extendedBoolean := false
+	targetName := false
+	allowEmpty := false
+	rest := false
+	reference := false
+	deprecated := ""
+	var minBound, maxBound *Bound
+	var separator rune
+	var requiredIf *Condition
 
 	for _, tagOption := range markerTagValues[1:] {
 
@@ -42,6 +115,110 @@ func ExtractArgument(structField reflect.StructField) (Argument, error) {
 		if tagOption == "useValueSyntax" {
 			useValueSyntax = true
 		}
+
+		if tagOption == "raw" {
+			raw = true
+		}
+
+		if tagOption == "targetName" {
+			targetName = true
+		}
+
+		if tagOption == "allowEmpty" {
+			allowEmpty = true
+		}
+
+		if tagOption == "rest" {
+			rest = true
+		}
+
+		if tagOption == "reference" {
+			reference = true
+		}
+
+		if strings.HasPrefix(tagOption, "deprecated=") {
+			deprecated = strings.TrimSpace(strings.TrimPrefix(tagOption, "deprecated="))
+
+			if deprecated == "" {
+				return Argument{}, fmt.Errorf("'%s' field has invalid option %s, the deprecation message cannot be empty", fieldName, tagOption)
+			}
+		}
+
+		if strings.HasPrefix(tagOption, "minItems=") {
+			count, err := parseItemCount(tagOption)
+
+			if err != nil {
+				return Argument{}, err
+			}
+
+			minItems = count
+		}
+
+		if strings.HasPrefix(tagOption, "bool=") {
+			if tagOption != "bool=extended" {
+				return Argument{}, fmt.Errorf("'%s' field has unsupported option %s, it can be only bool=extended", fieldName, tagOption)
+			}
+
+			extendedBoolean = true
+		}
+
+		if strings.HasPrefix(tagOption, "requiredIf=") {
+			condition, err := parseCondition(strings.TrimPrefix(tagOption, "requiredIf="))
+
+			if err != nil {
+				return Argument{}, fmt.Errorf("'%s' field has invalid option %s : %w", fieldName, tagOption, err)
+			}
+
+			requiredIf = condition
+		}
+
+		if strings.HasPrefix(tagOption, "sep=") {
+			separatorText := strings.TrimPrefix(tagOption, "sep=")
+
+			if utf8.RuneCountInString(separatorText) != 1 {
+				return Argument{}, fmt.Errorf("'%s' field has invalid option %s, the separator must be a single character", fieldName, tagOption)
+			}
+
+			separator, _ = utf8.DecodeRuneInString(separatorText)
+		}
+
+		if strings.HasPrefix(tagOption, "min=") || strings.HasPrefix(tagOption, "minExclusive=") {
+			if minBound != nil {
+				return Argument{}, fmt.Errorf("'%s' field cannot have both min and minExclusive options", fieldName)
+			}
+
+			bound, err := parseBound(tagOption)
+
+			if err != nil {
+				return Argument{}, fmt.Errorf("'%s' field has invalid option %s : %w", fieldName, tagOption, err)
+			}
+
+			minBound = bound
+		}
+
+		if strings.HasPrefix(tagOption, "max=") || strings.HasPrefix(tagOption, "maxExclusive=") {
+			if maxBound != nil {
+				return Argument{}, fmt.Errorf("'%s' field cannot have both max and maxExclusive options", fieldName)
+			}
+
+			bound, err := parseBound(tagOption)
+
+			if err != nil {
+				return Argument{}, fmt.Errorf("'%s' field has invalid option %s : %w", fieldName, tagOption, err)
+			}
+
+			maxBound = bound
+		}
+
+		if strings.HasPrefix(tagOption, "maxItems=") {
+			count, err := parseItemCount(tagOption)
+
+			if err != nil {
+				return Argument{}, err
+			}
+
+			maxItems = count
+		}
 	}
 
 	if ValueArgument != fieldName && syntaxFree {
@@ -56,8 +233,16 @@ func ExtractArgument(structField reflect.StructField) (Argument, error) {
 		return Argument{}, fmt.Errorf("'Value' cannot have both syntaxFree and useValueSyntax options at the same time")
 	}
 
+	if ValueArgument != fieldName && raw {
+		return Argument{}, fmt.Errorf("'Value' field can only have raw option")
+	}
+
+	if raw && (syntaxFree || useValueSyntax) {
+		return Argument{}, fmt.Errorf("'Value' cannot have raw option with syntaxFree or useValueSyntax options")
+	}
+
 	fieldType := structField.Type
-	argumentTypeInfo, err := GetArgumentTypeInfo(fieldType)
+	argumentTypeInfo, err := getArgumentTypeInfo(fieldType, visiting)
 
 	if err != nil {
 		return Argument{}, err
@@ -67,6 +252,54 @@ func ExtractArgument(structField reflect.StructField) (Argument, error) {
 		return Argument{}, fmt.Errorf("'Value' field with syntaxFree option can be only string")
 	}
 
+	if raw && (argumentTypeInfo.ActualType != StringType || fieldType.Kind() == reflect.Ptr) {
+		return Argument{}, fmt.Errorf("'Value' field with raw option can be only string")
+	}
+
+	if (minItems != 0 || maxItems != 0) && argumentTypeInfo.ActualType != SliceType && argumentTypeInfo.ActualType != MapType {
+		return Argument{}, fmt.Errorf("'%s' field with minItems or maxItems option can be only slice or map", fieldName)
+	}
+
+	if maxItems != 0 && minItems > maxItems {
+		return Argument{}, fmt.Errorf("'%s' field cannot have minItems greater than maxItems", fieldName)
+	}
+
+	if (minBound != nil || maxBound != nil) && argumentTypeInfo.ActualType != IntegerType && argumentTypeInfo.ActualType != FloatType {
+		return Argument{}, fmt.Errorf("'%s' field with min or max option can be only integer or float", fieldName)
+	}
+
+	if minBound != nil && maxBound != nil && minBound.Value > maxBound.Value {
+		return Argument{}, fmt.Errorf("'%s' field cannot have min greater than max", fieldName)
+	}
+
+	if extendedBoolean && argumentTypeInfo.ActualType != BoolType {
+		return Argument{}, fmt.Errorf("'%s' field with bool=extended option can be only bool", fieldName)
+	}
+
+	argumentTypeInfo.ExtendedBoolean = extendedBoolean
+
+	if separator != 0 && argumentTypeInfo.ActualType != SliceType {
+		return Argument{}, fmt.Errorf("'%s' field with sep option can be only slice", fieldName)
+	}
+
+	argumentTypeInfo.Separator = separator
+
+	if allowEmpty && (fieldType.Kind() == reflect.Ptr || argumentTypeInfo.ActualType != SliceType && argumentTypeInfo.ActualType != MapType) {
+		return Argument{}, fmt.Errorf("'%s' field with allowEmpty option can be only slice or map", fieldName)
+	}
+
+	if allowEmpty && (optionalOption || requiredIf != nil || minItems != 0) {
+		return Argument{}, fmt.Errorf("'%s' field cannot have allowEmpty option with optional, requiredIf or minItems options", fieldName)
+	}
+
+	if targetName && argumentTypeInfo.ActualType != StringType {
+		return Argument{}, fmt.Errorf("'%s' field with targetName option can be only string", fieldName)
+	}
+
+	if rest && fieldType != restType {
+		return Argument{}, fmt.Errorf("'%s' field with rest option can be only map[string]interface{}", fieldName)
+	}
+
 	isPointer := false
 	isOptional := false
 
@@ -75,7 +308,8 @@ func ExtractArgument(structField reflect.StructField) (Argument, error) {
 		isOptional = true
 	}
 
-	optionalOption = optionalOption || isOptional
+	// the arguments required conditionally are checked after they are parsed
+	optionalOption = optionalOption || isOptional || requiredIf != nil || deprecated != ""
 
 	return Argument{
 		Name:           fieldName,
@@ -84,5 +318,56 @@ func ExtractArgument(structField reflect.StructField) (Argument, error) {
 		Required:       !optionalOption,
 		SyntaxFree:     syntaxFree,
 		UseValueSyntax: useValueSyntax,
+		Raw:            raw,
+		MinItems:       minItems,
+		MaxItems:       maxItems,
+		TargetName:     targetName,
+		RequiredIf:     requiredIf,
+		AllowEmpty:     allowEmpty,
+		Rest:           rest,
+		Min:            minBound,
+		Max:            maxBound,
+		Deprecated:     deprecated,
+		Reference:      reference,
+	}, nil
+}
+
+// parseCondition parses a condition in the form of argument==value.
+func parseCondition(text string) (*Condition, error) {
+	conditionParts := strings.SplitN(text, "==", 2)
+
+	if len(conditionParts) != 2 || strings.TrimSpace(conditionParts[0]) == "" {
+		return nil, fmt.Errorf("condition must be in the form of argument==value, got %q", text)
+	}
+
+	return &Condition{
+		Argument: strings.TrimSpace(conditionParts[0]),
+		Value:    strings.TrimSpace(conditionParts[1]),
 	}, nil
 }
+
+// parseBound parses a bound option such as 'min=-273.15' or 'maxExclusive=100'.
+func parseBound(tagOption string) (*Bound, error) {
+	optionParts := strings.SplitN(tagOption, "=", 2)
+	value, err := strconv.ParseFloat(optionParts[1], 64)
+
+	if err != nil {
+		return nil, fmt.Errorf("bound must be a number, got %q", optionParts[1])
+	}
+
+	return &Bound{
+		Value:     value,
+		Exclusive: strings.HasSuffix(optionParts[0], "Exclusive"),
+	}, nil
+}
+
+func parseItemCount(tagOption string) (int, error) {
+	optionParts := strings.SplitN(tagOption, "=", 2)
+	count, err := strconv.Atoi(optionParts[1])
+
+	if err != nil || count < 0 {
+		return 0, fmt.Errorf("%s option must be a non-negative integer, got %q", optionParts[0], optionParts[1])
+	}
+
+	return count, nil
+}
@@ -16,13 +16,32 @@ limitations under the License.
 package main
 
 import (
+	"encoding/json"
+	"fmt"
 	"github.com/procyon-projects/marker"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
+var errorFormat = textErrorFormat
+
+// FileErrors keeps the errors which belong to the same file.
+type FileErrors struct {
+	FileName string       `json:"file"`
+	Count    int          `json:"count"`
+	Errors   []ErrorEntry `json:"errors"`
+}
+
+// ErrorEntry is an error with its position in the file.
+type ErrorEntry struct {
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
 // getPackageDirectories finds the go module directory and returns
 // the package directories.
 func getPackageDirectories() ([]string, error) {
@@ -85,19 +104,102 @@ func findDirectoriesWithGoFiles(root string) ([]string, error) {
 	return dirs, nil
 }
 
-// printErrors prints error(s) if any error exists after processing markers.
+// validateErrorFormat returns an error if the error format is not supported.
+func validateErrorFormat() error {
+	if errorFormat != textErrorFormat && errorFormat != jsonErrorFormat {
+		return fmt.Errorf("error format '%s' is not supported, it must be '%s' or '%s'", errorFormat, textErrorFormat, jsonErrorFormat)
+	}
+
+	return nil
+}
+
+// printErrors prints error(s) grouped by file if any error exists after processing markers.
+// The errors are printed as JSON if the error format is json.
 func printErrors(errorList marker.ErrorList) {
 	if errorList == nil || len(errorList) == 0 {
 		return
 	}
 
+	fileErrors := groupErrorsByFile(errorList)
+
+	if errorFormat == jsonErrorFormat {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+
+		if err := encoder.Encode(fileErrors); err != nil {
+			log.Println(err)
+		}
+
+		return
+	}
+
+	for _, fileError := range fileErrors {
+		log.Printf("%s : %d error(s)\n", fileError.FileName, fileError.Count)
+
+		for _, entry := range fileError.Errors {
+			log.Printf("%s (%d:%d) : %s\n", fileError.FileName, entry.Line, entry.Column, entry.Message)
+		}
+	}
+}
+
+// groupErrorsByFile groups the errors in the given error list by their file names.
+// The files and the errors in each file are sorted by their names and positions.
+func groupErrorsByFile(errorList marker.ErrorList) []FileErrors {
+	fileErrorMap := make(map[string]*FileErrors)
+	collectFileErrors(errorList, fileErrorMap)
+
+	fileErrors := make([]FileErrors, 0, len(fileErrorMap))
+
+	for _, fileError := range fileErrorMap {
+		sort.SliceStable(fileError.Errors, func(i, j int) bool {
+			if fileError.Errors[i].Line != fileError.Errors[j].Line {
+				return fileError.Errors[i].Line < fileError.Errors[j].Line
+			}
+
+			return fileError.Errors[i].Column < fileError.Errors[j].Column
+		})
+
+		fileError.Count = len(fileError.Errors)
+		fileErrors = append(fileErrors, *fileError)
+	}
+
+	sort.Slice(fileErrors, func(i, j int) bool {
+		return fileErrors[i].FileName < fileErrors[j].FileName
+	})
+
+	return fileErrors
+}
+
+// collectFileErrors adds the errors in the given error list into the file errors,
+// the errors without any position are added with an empty file name.
+func collectFileErrors(errorList marker.ErrorList, fileErrorMap map[string]*FileErrors) {
 	for _, err := range errorList {
+		var fileName string
+		var entry ErrorEntry
+
 		switch typedErr := err.(type) {
-		case marker.ParserError:
-			pos := typedErr.Position
-			log.Printf("%s (%d:%d) : %s\n", typedErr.FileName, pos.Line, pos.Column, typedErr.Error())
 		case marker.ErrorList:
-			printErrors(typedErr)
+			collectFileErrors(typedErr, fileErrorMap)
+			continue
+		case marker.ParserError:
+			fileName = typedErr.FileName
+			entry = ErrorEntry{
+				Line:    typedErr.Position.Line(),
+				Column:  typedErr.Position.Column(),
+				Message: typedErr.Error(),
+			}
+		default:
+			entry = ErrorEntry{
+				Message: err.Error(),
+			}
+		}
+
+		if _, ok := fileErrorMap[fileName]; !ok {
+			fileErrorMap[fileName] = &FileErrors{
+				FileName: fileName,
+			}
 		}
+
+		fileErrorMap[fileName].Errors = append(fileErrorMap[fileName].Errors, entry)
 	}
 }
@@ -13,4 +13,7 @@ const (
 	templateRootFolderName      = "marker-processor-template-1.0.0"
 	templateCmdFolderName       = "cmd"
 	templateProcessorFolderName = "processor-name"
+
+	textErrorFormat = "text"
+	jsonErrorFormat = "json"
 )
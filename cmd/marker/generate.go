@@ -30,6 +30,11 @@ var generateCmd = &cobra.Command{
 	Short: "Generate Go files by processing markers",
 	Long:  `The generate command helps your code generation process by running marker processors`,
 	Run: func(cmd *cobra.Command, args []string) {
+		if err := validateErrorFormat(); err != nil {
+			log.Println(err)
+			return
+		}
+
 		dirs, err := getPackageDirectories()
 
 		if err != nil {
@@ -69,7 +74,7 @@ var generateCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(generateCmd)
 
-	generateCmd.Flags().StringVarP(&outputPath, "output", "o", "", "output path")
+	generateCmd.Flags().StringVarP(&outputPath, "output", "o", "", "output path, it can be a template such as '{{.Package}}_gen.go' expanded for each package and processor")
 	err := generateCmd.MarkFlagRequired("output")
 
 	if err != nil {
@@ -78,4 +83,5 @@ func init() {
 
 	generateCmd.Flags().StringVarP(&packageName, "package", "p", "auto_generated", "package name")
 	generateCmd.Flags().StringSliceVarP(&options, "args", "a", options, "extra arguments for marker processors (key-value separated by comma)")
+	generateCmd.Flags().StringVar(&errorFormat, "error-format", textErrorFormat, "format of the marker errors, 'text' or 'json'")
 }
@@ -16,17 +16,21 @@ limitations under the License.
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/procyon-projects/marker"
 	"log"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
+	"text/template"
 )
 
 type MarkerProcessor struct {
-	Module  string
-	Version string
-	Command string
+	Module   string
+	Version  string
+	Commands []string
 }
 
 // Register your marker definitions.
@@ -84,30 +88,52 @@ func collectMarkers(collector *marker.Collector, pkgs []*marker.Package) error {
 			}
 
 			for _, value := range importMarkers {
-				importMarker := value.(marker.ImportMarker)
-				pkgId := importMarker.GetPkgId()
+				addProcessor(value.(marker.ImportMarker))
+			}
 
-				_, ok := processors[pkgId]
+		}
+	})
 
-				if !ok {
-					command := importMarker.GetCommand()
+	return marker.NewErrorList(validationErrors)
+}
 
-					if command == "" {
-						command = importMarker.Value
-					}
+// addProcessor adds the commands of the given import marker into the processor of its module.
+// If the import marker does not specify any command, its value is used as the command.
+func addProcessor(importMarker marker.ImportMarker) {
+	pkgId := importMarker.GetPkgId()
+	commands := importMarker.GetCommand()
 
-					processors[pkgId] = MarkerProcessor{
-						Module:  pkgId,
-						Version: importMarker.GetPkgVersion(),
-						Command: command,
-					}
-				}
-			}
+	if len(commands) == 0 {
+		commands = []string{importMarker.Value}
+	}
+
+	processor, ok := processors[pkgId]
 
+	if !ok {
+		processor = MarkerProcessor{
+			Module:  pkgId,
+			Version: importMarker.GetPkgVersion(),
 		}
-	})
+	}
 
-	return marker.NewErrorList(validationErrors)
+	for _, command := range commands {
+		if !containsCommand(processor.Commands, command) {
+			processor.Commands = append(processor.Commands, command)
+		}
+	}
+
+	processors[pkgId] = processor
+}
+
+// containsCommand checks if the given command exists in the commands.
+func containsCommand(commands []string, command string) bool {
+	for _, existingCommand := range commands {
+		if existingCommand == command {
+			return true
+		}
+	}
+
+	return false
 }
 
 // ProcessMarkers gets the import markers in the given directories.
@@ -157,13 +183,63 @@ func fetchPackages() error {
 	return nil
 }
 
+// OutputTemplateData is the data used to expand the output path template.
+type OutputTemplateData struct {
+	// Package is the name of the package directory.
+	Package string
+	// Dir is the package directory.
+	Dir string
+	// Processor is the command of the marker processor.
+	Processor string
+	// Module is the module of the marker processor.
+	Module string
+}
+
 // generateCode runs the marker processors to generate code
 func generateCode(dirs []string) {
+	if !isOutputTemplate(outputPath) {
+		err := createOutputDir(outputPath)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		runProcessors(getGenerateArgs(outputPath, dirs))
+		return
+	}
+
+	// each command is run for each package so that the outputs do not collide
+	for _, processor := range processors {
+		for _, command := range processor.Commands {
+			for _, dir := range dirs {
+				output, err := expandOutputPath(outputPath, OutputTemplateData{
+					Package:   filepath.Base(dir),
+					Dir:       dir,
+					Processor: command,
+					Module:    processor.Module,
+				})
+
+				if err == nil {
+					err = createOutputDir(output)
+				}
+
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				runProcessor(command, getGenerateArgs(output, []string{dir}))
+			}
+		}
+	}
+}
+
+// getGenerateArgs returns the arguments passed to the marker processors to generate code
+func getGenerateArgs(output string, dirs []string) []string {
 	args := make([]string, 0)
 
 	args = append(args, "generate")
 	args = append(args, "--output")
-	args = append(args, outputPath)
+	args = append(args, output)
 	args = append(args, "--path")
 	args = append(args, strings.Join(dirs, ","))
 
@@ -172,7 +248,53 @@ func generateCode(dirs []string) {
 		args = append(args, strings.Join(options, ","))
 	}
 
-	runProcessors(args)
+	return args
+}
+
+// isOutputTemplate checks if the given output path is a template such as '{{.Package}}_gen.go'.
+func isOutputTemplate(output string) bool {
+	return strings.Contains(output, "{{")
+}
+
+// expandOutputPath expands the given output path template by using the given data.
+func expandOutputPath(output string, data OutputTemplateData) (string, error) {
+	tmpl, err := template.New("output").Option("missingkey=error").Parse(output)
+
+	if err != nil {
+		return "", fmt.Errorf("output path template '%s' is not valid : %s", output, err.Error())
+	}
+
+	var buffer bytes.Buffer
+	err = tmpl.Execute(&buffer, data)
+
+	if err != nil {
+		return "", fmt.Errorf("output path template '%s' could not be expanded : %s", output, err.Error())
+	}
+
+	return buffer.String(), nil
+}
+
+// createOutputDir creates the directories of the given output path if they do not exist.
+// The output path such as 'gen/{{.Package}}' which ends with a path separator or has no
+// file extension is a directory, and it is created as well. Otherwise, the output path is
+// a file such as '{{.Package}}_gen.go', and only its parent directories are created.
+func createOutputDir(output string) error {
+	dir := filepath.Dir(output)
+
+	if isOutputDir(output) {
+		dir = filepath.Clean(output)
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return fmt.Errorf("output directory '%s' could not be created : %s", dir, err.Error())
+	}
+
+	return nil
+}
+
+// isOutputDir checks if the given output path is a directory rather than a file.
+func isOutputDir(output string) bool {
+	return strings.HasSuffix(output, "/") || strings.HasSuffix(output, string(filepath.Separator)) || filepath.Ext(output) == ""
 }
 
 // validate runs the marker processors to validate markers
@@ -191,24 +313,30 @@ func validate(dirs []string) {
 	runProcessors(args)
 }
 
-// runProcessor runs processors by passing given args
+// runProcessors runs each command of the processors by passing given args
 func runProcessors(args []string) {
 	for _, processor := range processors {
-		cmd := exec.Command(processor.Command, args...)
-		output, err := cmd.CombinedOutput()
-
-		if err != nil {
-			log.Printf("An error occurred while running command '%s %s' : ", processor.Command, strings.Join(args, " "))
-			log.Fatalf(err.Error())
+		for _, command := range processor.Commands {
+			runProcessor(command, args)
 		}
+	}
+}
 
-		if output != nil {
-			log.Printf(string(output))
-		}
+// runProcessor runs the given processor command by passing given args
+func runProcessor(command string, args []string) {
+	cmd := exec.Command(command, args...)
+	output, err := cmd.CombinedOutput()
 
-		if err != nil || output != nil {
-			log.Println()
-		}
+	if err != nil {
+		log.Printf("An error occurred while running command '%s %s' : ", command, strings.Join(args, " "))
+		log.Fatal(err.Error())
+	}
+
+	if output != nil {
+		log.Print(string(output))
+	}
 
+	if err != nil || output != nil {
+		log.Println()
 	}
 }
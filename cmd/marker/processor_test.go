@@ -0,0 +1,57 @@
+package main
+
+import (
+	"github.com/procyon-projects/marker"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAddProcessor(t *testing.T) {
+	processors = make(map[string]MarkerProcessor, 0)
+
+	addProcessor(marker.ImportMarker{
+		Value: "chrono",
+		Pkg:   "github.com/procyon-projects/chrono@v1.0.0:chrono-gen,chrono-mock",
+	})
+	addProcessor(marker.ImportMarker{
+		Value: "chrono-test",
+		Pkg:   "github.com/procyon-projects/chrono@v1.0.0:chrono-mock",
+	})
+	addProcessor(marker.ImportMarker{
+		Value: "goo",
+		Pkg:   "github.com/procyon-projects/goo",
+	})
+
+	assert.Equal(t, map[string]MarkerProcessor{
+		"github.com/procyon-projects/chrono": {
+			Module:   "github.com/procyon-projects/chrono",
+			Version:  "v1.0.0",
+			Commands: []string{"chrono-gen", "chrono-mock"},
+		},
+		"github.com/procyon-projects/goo": {
+			Module:   "github.com/procyon-projects/goo",
+			Commands: []string{"goo"},
+		},
+	}, processors)
+}
+
+func TestCreateOutputDir(t *testing.T) {
+	root := t.TempDir()
+
+	for _, output := range []string{"gen/{{.Package}}", "mock/{{.Package}}/", "{{.Package}}/{{.Package}}_gen.go"} {
+		expanded, err := expandOutputPath(filepath.Join(root, output), OutputTemplateData{Package: "books"})
+		assert.Nil(t, err)
+		assert.Nil(t, createOutputDir(expanded), output)
+	}
+
+	for _, dir := range []string{"gen/books", "mock/books", "books"} {
+		info, err := os.Stat(filepath.Join(root, dir))
+		assert.Nil(t, err, dir)
+		assert.True(t, info.IsDir(), dir)
+	}
+
+	_, err := os.Stat(filepath.Join(root, "books", "books_gen.go"))
+	assert.True(t, os.IsNotExist(err))
+}
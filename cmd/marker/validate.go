@@ -28,6 +28,11 @@ var validateCmd = &cobra.Command{
 	Short: "Validate markers' syntax and arguments",
 	Long:  `The validate command helps you validate markers' syntax and arguments'`,
 	Run: func(cmd *cobra.Command, args []string) {
+		if err := validateErrorFormat(); err != nil {
+			log.Println(err)
+			return
+		}
+
 		var err error
 		var dirs []string
 
@@ -70,4 +75,5 @@ var validateCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(validateCmd)
 	validateCmd.Flags().StringSliceVarP(&validateArgs, "args", "a", validateArgs, "extra arguments for marker processors (key-value separated by comma)")
+	validateCmd.Flags().StringVar(&errorFormat, "error-format", textErrorFormat, "format of the marker errors, 'text' or 'json'")
 }
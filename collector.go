@@ -4,56 +4,559 @@ import (
 	"errors"
 	"fmt"
 	"go/ast"
+	"go/build"
+	"go/build/constraint"
+	"go/constant"
 	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
 	"strings"
 )
 
+// TestFileMode describes whether the markers in the test files are collected.
+type TestFileMode int
+
+const (
+	// IncludeTestFiles collects the markers in both the test files and the other files.
+	IncludeTestFiles TestFileMode = iota
+	// ExcludeTestFiles skips the markers in the test files.
+	ExcludeTestFiles
+	// OnlyTestFiles collects only the markers in the test files.
+	OnlyTestFiles
+)
+
 type Collector struct {
 	*Registry
+	// StructTagMarkers enables collecting the markers written in the 'marker' struct tags
+	// of fields such as `marker:"+name:arg=value"` in addition to the comments.
+	StructTagMarkers bool
+	// UnmatchedMarkers enables keeping the marker comments which do not match
+	// any registered definition. They can be retrieved by Unmatched.
+	UnmatchedMarkers bool
+	// BuildContext is the target build context such as GOOS, GOARCH and build tags.
+	// If it is set, the markers in the files not matching the build constraints of
+	// the context are skipped. Otherwise, the markers in all the loaded files are collected.
+	BuildContext *build.Context
+	// TestFiles describes whether the markers in the test files ending with '_test.go' are
+	// collected if the packages are loaded with the test files. All files are collected by default.
+	TestFiles TestFileMode
+	// Variables are the values of the variables referenced in the marker arguments as $NAME or
+	// ${NAME}. If it is set, the references are replaced with the values before the markers are
+	// parsed, and a reference to a missing variable is an error unless a default value is given
+	// as ${NAME:-fallback}, which is also used if the variable is empty. The references in the
+	// quoted strings are kept as they are. Otherwise, the markers are parsed as they are.
+	Variables map[string]string
+	// BuildConstraintMarkers enables adding the '//go:build' line of each file into the package
+	// level markers of the file as a BuildConstraint value named BuildConstraintMarkerName.
+	BuildConstraintMarkers bool
+	// RecordParents enables keeping the enclosing declaration of each node which can carry
+	// markers, such as the type specification of a struct field. They can be retrieved by Parents.
+	RecordParents bool
+	// DisabledMarkers are the names of the registered markers which are skipped as if they
+	// are not registered, so that a registry can be used for collecting different markers.
+	DisabledMarkers map[string]bool
+
+	unmatched      []UnmatchedMarker
+	warnings       []Warning
+	parents        map[ast.Node]ast.Node
+	textRewriter   func(text string) string
+	errorHandler   func(err error)
+	postProcessors []func(markers map[ast.Node]MarkerValues) error
+}
+
+// BuildConstraintMarkerName is the name of the synthetic marker carrying the build constraint of a file.
+const BuildConstraintMarkerName = "go:build"
+
+// BuildConstraint is the build constraint of a file written in its '//go:build' line.
+type BuildConstraint struct {
+	// Text is the constraint expression such as 'linux && amd64'.
+	Text string
+	// Expr is the parsed constraint expression, it can be evaluated against the build tags.
+	Expr constraint.Expr
+}
+
+// UnmatchedMarker is a marker comment which does not match any registered definition.
+type UnmatchedMarker struct {
+	Text string
+	// RawText is the original text of the comment lines of the marker,
+	// including the comment delimiters and the line continuations.
+	RawText  string
+	Position token.Position
+	// End is the position after the last comment line of the marker.
+	End token.Position
+}
+
+// Warning is a problem in a marker which does not prevent it from being collected,
+// such as a deprecated argument.
+type Warning struct {
+	Message  string
+	Position token.Position
 }
 
 func NewCollector(registry *Registry) *Collector {
 	return &Collector{
-		registry,
+		Registry: registry,
 	}
 }
 
 func (collector *Collector) Collect(pkg *Package) (map[ast.Node]MarkerValues, error) {
+	return collector.collect(pkg, nil)
+}
+
+// CollectAll collects the markers of the given packages, and returns the markers of all
+// the packages in one map. Each package is collected with its own import markers. The errors
+// of the packages are returned in one ErrorList, and the markers of a package having errors
+// are not included. The unmatched markers, the warnings and the parents of all the packages are kept.
+func (collector *Collector) CollectAll(pkgs []*Package) (map[ast.Node]MarkerValues, error) {
+	results := make(map[ast.Node]MarkerValues)
+	var unmatched []UnmatchedMarker
+	var warnings []Warning
+	var parents map[ast.Node]ast.Node
+
+	var errs []error
+	for _, pkg := range pkgs {
+		markers, err := collector.Collect(pkg)
+
+		unmatched = append(unmatched, collector.unmatched...)
+		warnings = append(warnings, collector.warnings...)
 
+		for node, parent := range collector.parents {
+			if parents == nil {
+				parents = make(map[ast.Node]ast.Node)
+			}
+
+			parents[node] = parent
+		}
+
+		if errorList, ok := err.(ErrorList); ok {
+			errs = append(errs, errorList...)
+			continue
+		} else if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		for node, markerValues := range markers {
+			results[node] = markerValues
+		}
+	}
+
+	collector.unmatched = unmatched
+	collector.warnings = warnings
+	collector.parents = parents
+	return results, NewErrorList(errs)
+}
+
+// CollectIncremental functions like Collect, but it takes the comment index of the previous
+// collection of the package, and the marker comments of the files which are not parsed again
+// are taken from the index instead of walking their syntax trees. It returns the index to be
+// given to the next collection, a new index is created if the given index is nil.
+func (collector *Collector) CollectIncremental(pkg *Package, index *CommentIndex) (map[ast.Node]MarkerValues, *CommentIndex, error) {
+	if index == nil {
+		index = NewCommentIndex()
+	}
+
+	markers, err := collector.collect(pkg, index)
+	return markers, index, err
+}
+
+// collect collects the markers of the given package by using the given comment index
+// if it is not nil.
+func (collector *Collector) collect(pkg *Package, index *CommentIndex) (map[ast.Node]MarkerValues, error) {
 	if pkg == nil {
 		return nil, errors.New("pkg(package) cannot be nil")
 	}
 
-	nodeMarkers := collector.collectPackageMarkerComments(pkg)
+	collector.unmatched = nil
+	collector.warnings = nil
+	collector.parents = nil
+
+	nodeMarkers := collector.collectPackageMarkerComments(pkg, index)
 	markers, err := collector.parseMarkerComments(pkg, nodeMarkers)
 
 	if err != nil {
 		return nil, err
 	}
 
+	if collector.BuildConstraintMarkers {
+		err = collector.collectBuildConstraints(pkg, markers)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, postProcessor := range collector.postProcessors {
+		err = postProcessor(markers)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return markers, nil
 }
 
-func (collector *Collector) collectPackageMarkerComments(pkg *Package) map[ast.Node][]markerComment {
+// collectBuildConstraints adds the build constraints of the files in the given package
+// into the package level markers of the files.
+func (collector *Collector) collectBuildConstraints(pkg *Package, markers map[ast.Node]MarkerValues) error {
+	var errs []error
+
+	for _, file := range pkg.Syntax {
+		if !collector.matchBuildContext(pkg, file) || !collector.matchTestFileMode(pkg, file) {
+			continue
+		}
+
+		for _, commentGroup := range file.Comments {
+			// the build constraints must appear before the package clause
+			if commentGroup.Pos() > file.Package {
+				break
+			}
+
+			for _, comment := range commentGroup.List {
+				if !constraint.IsGoBuild(comment.Text) {
+					continue
+				}
+
+				expr, err := constraint.Parse(comment.Text)
+
+				if err != nil {
+					errs = collector.reportError(errs, toParseError(err, comment, pkg.Fset.Position(comment.Pos()), pkg.Fset.Position(comment.End())))
+					continue
+				}
+
+				if markers[file] == nil {
+					markers[file] = make(MarkerValues)
+				}
+
+				markers[file][BuildConstraintMarkerName] = append(markers[file][BuildConstraintMarkerName], BuildConstraint{
+					Text: expr.String(),
+					Expr: expr,
+				})
+			}
+		}
+	}
+
+	return NewErrorList(errs)
+}
+
+// CollectWithHandler functions like Collect, and it also passes each parse and validation
+// error to the given handler as soon as it is found. The errors passed to the handler are
+// the same positioned errors as the ones in the returned ErrorList.
+func (collector *Collector) CollectWithHandler(pkg *Package, handler func(err error)) (map[ast.Node]MarkerValues, error) {
+	collector.errorHandler = handler
+	defer func() {
+		collector.errorHandler = nil
+	}()
+
+	return collector.Collect(pkg)
+}
+
+// reportError appends the given error to the errors, and passes each error in it
+// to the error handler if there is any.
+func (collector *Collector) reportError(errs []error, err error) []error {
+	if collector.errorHandler != nil {
+		handleErrors(err, collector.errorHandler)
+	}
+
+	return append(errs, err)
+}
+
+// handleErrors passes the given error to the handler, the elements of an ErrorList
+// are passed one by one.
+func handleErrors(err error, handler func(err error)) {
+	if errorList, ok := err.(ErrorList); ok {
+		for _, errorElement := range errorList {
+			handleErrors(errorElement, handler)
+		}
+
+		return
+	}
+
+	handler(err)
+}
+
+// Validate parses the markers in the given package and validates them without keeping
+// the marker values. All the parse and validation errors are returned as an ErrorList.
+func (collector *Collector) Validate(pkg *Package) error {
+	_, err := collector.Collect(pkg)
+
+	if err == nil {
+		return nil
+	}
+
+	if errorList, ok := err.(ErrorList); ok {
+		return errorList
+	}
+
+	return ErrorList{err}
+}
+
+// SetTextRewriter sets the function rewriting the text of each marker comment
+// before it is looked up and parsed, such as renaming a deprecated marker or argument.
+// The rewriter is called after the import aliases are replaced with the marker names.
+func (collector *Collector) SetTextRewriter(rewriter func(text string) string) {
+	collector.textRewriter = rewriter
+}
+
+// AddPostProcessor adds a function processing the markers of all nodes at the end of
+// Collect, such as sorting or filtering the marker values. The post processors are called
+// in the order they are added, and an error returned by any of them aborts the collection.
+func (collector *Collector) AddPostProcessor(postProcessor func(markers map[ast.Node]MarkerValues) error) {
+	collector.postProcessors = append(collector.postProcessors, postProcessor)
+}
+
+// Unmatched returns the marker comments which do not match any registered definition
+// in the last collected packages, ordered by their positions. The UnmatchedMarkers option
+// must be enabled to keep them, otherwise it returns nil.
+func (collector *Collector) Unmatched() []UnmatchedMarker {
+	return collector.unmatched
+}
+
+// Warnings returns the warnings found in the last collected packages, such as the deprecated
+// arguments given in the markers.
+func (collector *Collector) Warnings() []Warning {
+	return collector.warnings
+}
+
+// addDeprecationWarnings adds a warning for each deprecated argument given in the marker.
+func (collector *Collector) addDeprecationWarnings(pkg *Package, definition *Definition, seen map[string]bool, markerComment markerComment) {
+	argumentNames := make([]string, 0)
+
+	for argumentName, argument := range definition.Output.Fields {
+		if argument.Deprecated != "" && seen[argumentName] {
+			argumentNames = append(argumentNames, argumentName)
+		}
+	}
+
+	sort.Strings(argumentNames)
+
+	for _, argumentName := range argumentNames {
+		collector.warnings = append(collector.warnings, Warning{
+			Message:  fmt.Sprintf("argument %q of marker %q is deprecated: %s", argumentName, "+"+definition.Name, definition.Output.Fields[argumentName].Deprecated),
+			Position: pkg.Fset.Position(markerComment.Pos()),
+		})
+	}
+}
+
+// Parents returns the enclosing declarations of the nodes in the last collected packages,
+// such as the type specification of a field or the declaration of a type specification.
+// The declarations are a file, a GenDecl, a TypeSpec, a FuncDecl or a FuncLit. The
+// RecordParents option must be enabled to keep them, otherwise it returns nil.
+func (collector *Collector) Parents() map[ast.Node]ast.Node {
+	return collector.parents
+}
+
+// MarkedNode is a node carrying a marker with the values of the marker.
+type MarkedNode struct {
+	Node   ast.Node
+	Values []interface{}
+}
+
+// FindNodesWithMarker returns the nodes carrying the marker with the given name
+// in the results of Collect, ordered by their positions.
+func FindNodesWithMarker(results map[ast.Node]MarkerValues, name string) []MarkedNode {
+	markedNodes := make([]MarkedNode, 0)
+
+	for node, markerValues := range results {
+		if values, ok := markerValues[name]; ok && len(values) != 0 {
+			markedNodes = append(markedNodes, MarkedNode{
+				Node:   node,
+				Values: values,
+			})
+		}
+	}
+
+	sort.Slice(markedNodes, func(i, j int) bool {
+		return markedNodes[i].Node.Pos() < markedNodes[j].Node.Pos()
+	})
+
+	return markedNodes
+}
+
+// ResolveType returns the declaration of the type with the given name in the given package
+// and the markers of the type in the results of Collect. It makes it possible to follow
+// a type reference such as the type of a field to the markers of the referenced type.
+// The name must be the name of a type declared at the top level of the package.
+func ResolveType(pkg *Package, results map[ast.Node]MarkerValues, name string) (ast.Node, MarkerValues, bool) {
+	if pkg == nil {
+		return nil, nil, false
+	}
+
+	for _, file := range pkg.Syntax {
+		for _, declaration := range file.Decls {
+			genDecl, ok := declaration.(*ast.GenDecl)
+
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+
+			for _, spec := range genDecl.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+
+				if typeSpec.Name.Name == name {
+					return typeSpec, results[typeSpec], true
+				}
+			}
+		}
+	}
+
+	return nil, nil, false
+}
+
+// CommentIndex keeps the marker comments of the files of a package collected by
+// CollectIncremental. The files are identified by their syntax trees, so the marker
+// comments of a file are collected again only if the file is parsed again.
+type CommentIndex struct {
+	files map[*ast.File]*fileMarkerComments
+}
+
+// fileMarkerComments are the marker comments and the parents of the nodes in a file.
+type fileMarkerComments struct {
+	nodeMarkers      map[ast.Node][]markerComment
+	parents          map[ast.Node]ast.Node
+	structTagMarkers bool
+}
+
+// NewCommentIndex returns a new empty comment index.
+func NewCommentIndex() *CommentIndex {
+	return &CommentIndex{
+		files: make(map[*ast.File]*fileMarkerComments),
+	}
+}
+
+// Len returns the number of the files in the index.
+func (index *CommentIndex) Len() int {
+	return len(index.files)
+}
+
+func (collector *Collector) collectPackageMarkerComments(pkg *Package, index *CommentIndex) map[ast.Node][]markerComment {
 	packageNodeMarkers := make(map[ast.Node][]markerComment)
+	files := make(map[*ast.File]*fileMarkerComments, len(pkg.Syntax))
 
 	for _, file := range pkg.Syntax {
-		fileNodeMarkers := collector.collectFileMarkerComments(file)
+		if !collector.matchBuildContext(pkg, file) || !collector.matchTestFileMode(pkg, file) {
+			continue
+		}
+
+		var fileComments *fileMarkerComments
+
+		if index != nil {
+			fileComments = index.files[file]
+		}
+
+		if fileComments == nil || fileComments.structTagMarkers != collector.StructTagMarkers {
+			fileComments = collector.collectFileMarkerComments(file)
+		}
 
-		for node, markers := range fileNodeMarkers {
+		files[file] = fileComments
+
+		if collector.RecordParents {
+			if collector.parents == nil {
+				collector.parents = make(map[ast.Node]ast.Node)
+			}
+
+			for node, parent := range fileComments.parents {
+				collector.parents[node] = parent
+			}
+		}
+
+		for node, markers := range fileComments.nodeMarkers {
 			packageNodeMarkers[node] = append(packageNodeMarkers[node], markers...)
 		}
 	}
 
+	// the files which are parsed again or removed are not kept in the index
+	if index != nil {
+		index.files = files
+	}
+
 	return packageNodeMarkers
 }
 
-func (collector *Collector) collectFileMarkerComments(file *ast.File) map[ast.Node][]markerComment {
+// matchTestFileMode reports whether the given file is collected by the test file mode.
+func (collector *Collector) matchTestFileMode(pkg *Package, file *ast.File) bool {
+	if collector.TestFiles == IncludeTestFiles {
+		return true
+	}
+
+	isTestFile := false
+
+	if tokenFile := pkg.Fset.File(file.Pos()); tokenFile != nil {
+		isTestFile = strings.HasSuffix(tokenFile.Name(), "_test.go")
+	}
+
+	if collector.TestFiles == OnlyTestFiles {
+		return isTestFile
+	}
+
+	return !isTestFile
+}
+
+// matchBuildContext reports whether the given file matches the build constraints
+// of the build context. The files which cannot be checked are considered as matched.
+func (collector *Collector) matchBuildContext(pkg *Package, file *ast.File) bool {
+	if collector.BuildContext == nil {
+		return true
+	}
+
+	path := pkg.Fset.Position(file.Pos()).Filename
+
+	if path == "" {
+		return true
+	}
+
+	matched, err := collector.BuildContext.MatchFile(filepath.Dir(path), filepath.Base(path))
+
+	if err != nil {
+		return true
+	}
+
+	return matched
+}
+
+func (collector *Collector) collectFileMarkerComments(file *ast.File) *fileMarkerComments {
 	visitor := newCommentVisitor(file.Comments)
 	ast.Walk(visitor, file)
 	visitor.nodeMarkers[file] = visitor.packageMarkers
 
-	return visitor.nodeMarkers
+	if collector.StructTagMarkers {
+		collector.collectStructTagMarkers(file, visitor.nodeMarkers)
+	}
+
+	return &fileMarkerComments{
+		nodeMarkers:      visitor.nodeMarkers,
+		parents:          visitor.parents,
+		structTagMarkers: collector.StructTagMarkers,
+	}
+}
+
+func (collector *Collector) collectStructTagMarkers(file *ast.File, nodeMarkers map[ast.Node][]markerComment) {
+	ast.Inspect(file, func(node ast.Node) bool {
+		structType, ok := node.(*ast.StructType)
+
+		if !ok || structType.Fields == nil {
+			return true
+		}
+
+		for _, field := range structType.Fields.List {
+			if markerComment := newStructTagMarkerComment(field.Tag); markerComment != nil {
+				nodeMarkers[field] = append(nodeMarkers[field], *markerComment)
+			}
+		}
+
+		return true
+	})
+}
+
+// referringMarker is a parsed marker whose arguments refer to the other markers on its node.
+type referringMarker struct {
+	definition    *Definition
+	value         interface{}
+	references    []markerReference
+	seen          map[string]bool
+	markerComment markerComment
 }
 
 func (collector *Collector) parseMarkerComments(pkg *Package, nodeMarkerComments map[ast.Node][]markerComment) (map[ast.Node]MarkerValues, error) {
@@ -79,15 +582,20 @@ func (collector *Collector) parseMarkerComments(pkg *Package, nodeMarkerComments
 		return nil, err
 	}
 
+	resolveConstant := newConstantResolver(pkg)
+
 	var errs []error
 	for node, markerComments := range nodeMarkerComments {
 
 		markerValues := make(MarkerValues)
+		definitions := make([]*Definition, 0)
+		// the markers having references are resolved after the other markers of the node
+		referringMarkers := make([]referringMarker, 0)
 		file := pkg.Fset.File(node.Pos())
 		importAliases := fileImportAliases[file]
 
 		for _, markerComment := range markerComments {
-			markerText := markerComment.Text()
+			markerText := collector.resolveMarkerSyntax(markerComment.Text())
 
 			// first we need to check if there is any import
 			aliasName, _, _ := splitMarker(markerText)
@@ -95,16 +603,33 @@ func (collector *Collector) parseMarkerComments(pkg *Package, nodeMarkerComments
 			// markers can be syntax free such as +build
 			aliasName = strings.Split(aliasName, " ")[0]
 
-			var definition *Definition
+			var pkgId string
 			if name, ok := importAliases[aliasName]; ok {
 				markerText = strings.Replace(markerText, fmt.Sprintf("+%s", aliasName), fmt.Sprintf("+%s", name), 1)
 				importMarker := importMarkers[aliasName]
-				definition = collector.Lookup(markerText, importMarker.GetPkgId())
-			} else {
-				definition = collector.Lookup(markerText, "")
+				pkgId = importMarker.GetPkgId()
+			}
+
+			if collector.textRewriter != nil {
+				markerText = collector.textRewriter(markerText)
+			}
+
+			definition := collector.Lookup(markerText, pkgId)
+
+			if definition != nil && collector.DisabledMarkers[definition.Name] {
+				definition = nil
 			}
 
 			if definition == nil {
+				if collector.UnmatchedMarkers {
+					collector.unmatched = append(collector.unmatched, UnmatchedMarker{
+						Text:     markerComment.Text(),
+						RawText:  markerComment.RawText(),
+						Position: pkg.Fset.Position(markerComment.Pos()),
+						End:      pkg.Fset.Position(markerComment.End()),
+					})
+				}
+
 				continue
 			}
 
@@ -151,44 +676,261 @@ func (collector *Collector) parseMarkerComments(pkg *Package, nodeMarkerComments
 					continue
 				}
 
+			case *ast.FuncLit:
+
+				if definition.Level&FunctionLiteralLevel != FunctionLiteralLevel {
+					continue
+				}
+
+			case *ast.ValueSpec:
+
+				if definition.Level&ValueLevel != ValueLevel {
+					continue
+				}
+
 			}
 
-			value, err := definition.Parse(markerText)
+			var value interface{}
+
+			if collector.Variables != nil {
+				// only the arguments are expanded, the marker name is kept as it is
+				prefix := "+" + definition.Name
+				var arguments string
+				arguments, err = expandVariables(strings.TrimPrefix(markerText, prefix), collector.Variables)
+				markerText = prefix + arguments
+
+				if err != nil {
+					position := pkg.Fset.Position(markerComment.Pos())
+					errs = collector.reportError(errs, toParseError(err, markerComment, position, pkg.Fset.Position(markerComment.End())))
+					continue
+				}
+			}
+
+			var references []markerReference
+			var seen map[string]bool
+			value, seen, references, err = definition.parse(markerText, resolveConstant)
+
+			if err == nil {
+				collector.addDeprecationWarnings(pkg, definition, seen, markerComment)
+			}
 
 			if err != nil {
-				position := pkg.Fset.Position(markerComment.Pos())
-				errs = append(errs, toParseError(err, markerComment, position))
+				err = definition.appendArgumentSignature(err)
+			} else if len(references) != 0 {
+				referringMarkers = append(referringMarkers, referringMarker{
+					definition:    definition,
+					value:         definition.setTargetName(value, getNodeName(node)),
+					references:    references,
+					seen:          seen,
+					markerComment: markerComment,
+				})
 				continue
+			} else {
+				value = definition.setTargetName(value, getNodeName(node))
+				value, err = normalizeMarker(value)
 			}
 
-			if marker, ok := value.(Marker); ok {
-				err = marker.Validate()
+			if err != nil {
+				position := pkg.Fset.Position(markerComment.Pos())
+				errs = collector.reportError(errs, toParseError(err, markerComment, position, pkg.Fset.Position(markerComment.End())))
+				continue
 			}
 
+			err = definition.validate(value, seen)
+
 			if err != nil {
 				position := pkg.Fset.Position(markerComment.Pos())
-				errs = append(errs, toParseError(err, markerComment, position))
+				errs = collector.reportError(errs, toParseError(err, markerComment, position, pkg.Fset.Position(markerComment.End())))
 				continue
 			}
 
+			if _, exists := markerValues[definition.Name]; !exists {
+				definitions = append(definitions, definition)
+			}
+
 			markerValues[definition.Name] = append(markerValues[definition.Name], value)
 		}
 
+		for _, referringMarker := range referringMarkers {
+			definition := referringMarker.definition
+			value, err := definition.resolveReferences(referringMarker.value, referringMarker.references, markerValues)
+
+			if err == nil {
+				value, err = normalizeMarker(value)
+			}
+
+			if err == nil {
+				err = definition.validate(value, referringMarker.seen)
+			}
+
+			if err != nil {
+				position := pkg.Fset.Position(referringMarker.markerComment.Pos())
+				errs = collector.reportError(errs, toParseError(err, referringMarker.markerComment, position, pkg.Fset.Position(referringMarker.markerComment.End())))
+				continue
+			}
+
+			if _, exists := markerValues[definition.Name]; !exists {
+				definitions = append(definitions, definition)
+			}
+
+			markerValues[definition.Name] = append(markerValues[definition.Name], value)
+		}
+
+		for _, definition := range definitions {
+			err := definition.ValidateNode(markerValues)
+
+			if err != nil {
+				position := pkg.Fset.Position(node.Pos())
+				errs = collector.reportError(errs, toParseError(err, node, position, pkg.Fset.Position(node.End())))
+			}
+		}
+
 		if len(markerValues) != 0 {
 			nodeMarkerValues[node] = markerValues
 		}
 
 	}
 
+	sort.Slice(collector.unmatched, func(i, j int) bool {
+		if collector.unmatched[i].Position.Filename != collector.unmatched[j].Position.Filename {
+			return collector.unmatched[i].Position.Filename < collector.unmatched[j].Position.Filename
+		}
+
+		return collector.unmatched[i].Position.Offset < collector.unmatched[j].Position.Offset
+	})
+
 	return nodeMarkerValues, NewErrorList(errs)
 }
 
+// newConstantResolver returns a resolver of the constants declared in the given package such
+// as 'MaxSize', and the exported constants of its imports such as 'math.MaxInt32'. It returns
+// nil if the package is not type-checked.
+func newConstantResolver(pkg *Package) constantResolver {
+	if pkg.Types == nil {
+		return nil
+	}
+
+	return func(name string) (constant.Value, bool) {
+		scope := pkg.Types.Scope()
+		qualifier := ""
+
+		if index := strings.Index(name, "."); index != -1 {
+			qualifier, name = name[:index], name[index+1:]
+			scope = nil
+
+			for _, imported := range pkg.Types.Imports() {
+				if imported.Name() == qualifier {
+					scope = imported.Scope()
+					break
+				}
+			}
+		}
+
+		if scope == nil {
+			return nil, false
+		}
+
+		constantObject, ok := scope.Lookup(name).(*types.Const)
+
+		if !ok || qualifier != "" && !constantObject.Exported() {
+			return nil, false
+		}
+
+		return constantObject.Val(), true
+	}
+}
+
+// ReceiverTypeName returns the name of the receiver type of the given method declaration
+// without its pointer and type parameters, such as 'Book' for 'func (b *Book) Title()'.
+// It returns an empty string if the declaration is not a method.
+func ReceiverTypeName(decl *ast.FuncDecl) string {
+	if decl == nil || decl.Recv == nil || len(decl.Recv.List) == 0 {
+		return ""
+	}
+
+	receiverType := decl.Recv.List[0].Type
+
+	for {
+		switch typedType := receiverType.(type) {
+		case *ast.StarExpr:
+			receiverType = typedType.X
+		case *ast.ParenExpr:
+			receiverType = typedType.X
+		case *ast.IndexExpr:
+			receiverType = typedType.X
+		case *ast.IndexListExpr:
+			receiverType = typedType.X
+		case *ast.Ident:
+			return typedType.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// getNodeName returns the identifier name of the given type, field or function node.
+// The name of an embedded field is the name of its type.
+func getNodeName(node ast.Node) string {
+	switch typedNode := node.(type) {
+	case *ast.TypeSpec:
+		return typedNode.Name.Name
+	case *ast.FuncDecl:
+		return typedNode.Name.Name
+	case *ast.ValueSpec:
+		return typedNode.Names[0].Name
+	case *ast.Field:
+		if len(typedNode.Names) != 0 {
+			return typedNode.Names[0].Name
+		}
+
+		typ := typedNode.Type
+
+		if starExpr, ok := typ.(*ast.StarExpr); ok {
+			typ = starExpr.X
+		}
+
+		switch typedType := typ.(type) {
+		case *ast.Ident:
+			return typedType.Name
+		case *ast.SelectorExpr:
+			return typedType.Sel.Name
+		}
+	}
+
+	return ""
+}
+
+// importOccurrence is the first import marker of a processor package.
+type importOccurrence struct {
+	alias    string
+	position token.Position
+}
+
 func (collector *Collector) parseImportMarkerComments(pkg *Package, nodeMarkerComments map[ast.Node][]markerComment) (map[ast.Node]MarkerValues, error) {
 	var errs []error
 	importNodeMarkers := make(map[ast.Node]MarkerValues)
 
-	for node, markerComments := range nodeMarkerComments {
+	nodes := make([]ast.Node, 0, len(nodeMarkerComments))
 
+	for node := range nodeMarkerComments {
+		nodes = append(nodes, node)
+	}
+
+	// the nodes are visited in the order of their positions to find the first imports
+	sort.Slice(nodes, func(i, j int) bool {
+		first, second := pkg.Fset.Position(nodes[i].Pos()), pkg.Fset.Position(nodes[j].Pos())
+
+		if first.Filename != second.Filename {
+			return first.Filename < second.Filename
+		}
+
+		return first.Offset < second.Offset
+	})
+
+	firstImports := make(map[string]importOccurrence)
+
+	for _, node := range nodes {
+		markerComments := nodeMarkerComments[node]
 		markerValues := make(MarkerValues)
 
 		for _, markerComment := range markerComments {
@@ -207,7 +949,7 @@ func (collector *Collector) parseImportMarkerComments(pkg *Package, nodeMarkerCo
 
 			if err != nil {
 				position := pkg.Fset.Position(markerComment.Pos())
-				errs = append(errs, toParseError(err, markerComment, position))
+				errs = collector.reportError(errs, toParseError(err, markerComment, position, pkg.Fset.Position(markerComment.End())))
 				continue
 			}
 
@@ -215,9 +957,13 @@ func (collector *Collector) parseImportMarkerComments(pkg *Package, nodeMarkerCo
 				err = marker.Validate()
 			}
 
+			if err == nil {
+				err = checkImportConflict(firstImports, value.(ImportMarker), pkg.Fset.Position(markerComment.Pos()))
+			}
+
 			if err != nil {
 				position := pkg.Fset.Position(markerComment.Pos())
-				errs = append(errs, toParseError(err, markerComment, position))
+				errs = collector.reportError(errs, toParseError(err, markerComment, position, pkg.Fset.Position(markerComment.End())))
 				continue
 			}
 
@@ -233,10 +979,40 @@ func (collector *Collector) parseImportMarkerComments(pkg *Package, nodeMarkerCo
 	return importNodeMarkers, NewErrorList(errs)
 }
 
+// checkImportConflict checks if the processor package of the given import marker has already
+// been imported in the same file, or it has been imported with another alias in another file.
+// The first imports are kept by the package ids in the given map.
+func checkImportConflict(firstImports map[string]importOccurrence, importMarker ImportMarker, position token.Position) error {
+	alias := importMarker.Alias
+
+	if alias == "" {
+		alias = importMarker.Value
+	}
+
+	firstImport, exists := firstImports[importMarker.GetPkgId()]
+
+	if !exists {
+		firstImports[importMarker.GetPkgId()] = importOccurrence{
+			alias:    alias,
+			position: position,
+		}
+		return nil
+	}
+
+	if firstImport.position.Filename == position.Filename {
+		return fmt.Errorf("processor with Pkg '%s' has already been imported at %s", importMarker.GetPkgId(), firstImport.position)
+	}
+
+	if firstImport.alias != alias {
+		return fmt.Errorf("processor with Pkg '%s' is imported as '%s', but it has already been imported as '%s' at %s", importMarker.GetPkgId(), alias, firstImport.alias, firstImport.position)
+	}
+
+	return nil
+}
+
 type AliasMap map[string]string
 
 func (collector *Collector) extractFileImportAliases(pkg *Package, importNodeMarkers map[ast.Node]MarkerValues) (map[*token.File]AliasMap, map[string]ImportMarker, error) {
-	var errs []error
 	var fileImportAliases = make(map[*token.File]AliasMap, 0)
 	var importMarkers = make(map[string]ImportMarker, 0)
 
@@ -258,20 +1034,11 @@ func (collector *Collector) extractFileImportAliases(pkg *Package, importNodeMar
 		}
 
 		aliasMap := make(AliasMap, 0)
-		pkgIdMap := make(map[string]bool, 0)
 
+		// the conflicting imports are reported while they are parsed
 		for _, marker := range markers {
 			importMarker := marker.(ImportMarker)
 
-			if _, ok := pkgIdMap[importMarker.GetPkgId()]; ok {
-				position := pkg.Fset.Position(node.Pos())
-				err := fmt.Errorf("processor with Pkg '%s' has alrealdy been imported", importMarker.GetPkgId())
-				errs = append(errs, toParseError(err, node, position))
-				continue
-			}
-
-			pkgIdMap[importMarker.GetPkgId()] = true
-
 			if importMarker.Alias == "" {
 				aliasMap[importMarker.Value] = importMarker.Value
 			} else {
@@ -283,5 +1050,5 @@ func (collector *Collector) extractFileImportAliases(pkg *Package, importNodeMar
 		fileImportAliases[file] = aliasMap
 	}
 
-	return fileImportAliases, importMarkers, NewErrorList(errs)
+	return fileImportAliases, importMarkers, nil
 }
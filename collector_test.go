@@ -1,6 +1,17 @@
 package marker
 
 import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"golang.org/x/tools/go/packages"
+	"os"
+	"sort"
+	"strings"
 	"testing"
 )
 
@@ -20,3 +31,1214 @@ func TestCollector_Collect(t *testing.T) {
 		}
 	})
 }
+
+type testFieldLevelMarker struct {
+	Name string `marker:"Name"`
+}
+
+func TestCollector_CollectStructTagMarkers(t *testing.T) {
+	pkgs, err := LoadPackages("./test/package2")
+	assert.Nil(t, err)
+
+	registry := NewRegistry()
+	err = registry.Register("marker:field-level", "", FieldLevel, &testFieldLevelMarker{})
+	assert.Nil(t, err)
+
+	collector := NewCollector(registry)
+
+	markers, err := collector.Collect(pkgs[0])
+	assert.Nil(t, err)
+	assert.Empty(t, fieldMarkers(markers))
+
+	collector.StructTagMarkers = true
+
+	markers, err = collector.Collect(pkgs[0])
+	assert.Nil(t, err)
+
+	values := fieldMarkers(markers)
+	assert.Len(t, values, 1)
+	assert.Equal(t, testFieldLevelMarker{Name: "title"}, values[0].Get("marker:field-level"))
+}
+
+func fieldMarkers(markers map[ast.Node]MarkerValues) []MarkerValues {
+	var values []MarkerValues
+
+	for node, markerValues := range markers {
+		if _, ok := node.(*ast.Field); ok {
+			values = append(values, markerValues)
+		}
+	}
+
+	return values
+}
+
+type testHandlerMarker struct {
+	Path string `marker:"Path"`
+}
+
+func TestCollector_CollectFunctionLiteralMarkers(t *testing.T) {
+	pkgs, err := LoadPackages("./test/package2")
+	assert.Nil(t, err)
+
+	registry := NewRegistry()
+	err = registry.Register("marker:handler", "", FunctionLiteralLevel, &testHandlerMarker{})
+	assert.Nil(t, err)
+
+	collector := NewCollector(registry)
+
+	markers, err := collector.Collect(pkgs[0])
+	assert.Nil(t, err)
+
+	var paths []string
+
+	for node, markerValues := range markers {
+		if _, ok := node.(*ast.FuncLit); ok {
+			paths = append(paths, markerValues.Get("marker:handler").(testHandlerMarker).Path)
+		}
+	}
+
+	assert.ElementsMatch(t, []string{"/books", "/authors", "/health"}, paths)
+}
+
+func TestCollector_Unmatched(t *testing.T) {
+	pkgs, err := LoadPackages("./test/package2")
+	assert.Nil(t, err)
+
+	registry := NewRegistry()
+	err = registry.Register("marker:handler", "", FunctionLiteralLevel, &testHandlerMarker{})
+	assert.Nil(t, err)
+
+	collector := NewCollector(registry)
+
+	_, err = collector.Collect(pkgs[0])
+	assert.Nil(t, err)
+	assert.Nil(t, collector.Unmatched())
+
+	collector.UnmatchedMarkers = true
+
+	_, err = collector.Collect(pkgs[0])
+	assert.Nil(t, err)
+
+	unmatched := collector.Unmatched()
+	assert.Len(t, unmatched, 1)
+	assert.Equal(t, "+deprecated Use Name instead", unmatched[0].Text)
+	assert.Equal(t, 5, unmatched[0].Position.Line)
+}
+
+type testEnumMarker struct {
+	Label string `marker:"label"`
+}
+
+func TestCollector_CollectValueSpecTrailingMarkers(t *testing.T) {
+	registry := NewRegistry()
+	err := registry.Register("enum", "", ValueLevel, &testEnumMarker{})
+	assert.Nil(t, err)
+
+	pkg := parseTestPackage(t, map[string]string{
+		"genre.go": `package test
+
+// Genre is the genre of a book.
+type Genre int
+
+const (
+	Fiction Genre = iota // +enum:label=Fiction
+	Poetry               // +enum:label=Poetry
+	Drama                // +enum:label=Drama
+)
+`,
+	})
+
+	results, err := NewCollector(registry).Collect(pkg)
+	assert.Nil(t, err)
+
+	labels := make(map[string]interface{})
+
+	for node, markerValues := range results {
+		if len(markerValues["enum"]) == 0 {
+			continue
+		}
+
+		// each trailing comment belongs to its own constant, not to the block or the next constant
+		valueSpec, ok := node.(*ast.ValueSpec)
+		assert.True(t, ok)
+		assert.Len(t, markerValues["enum"], 1)
+		labels[valueSpec.Names[0].Name] = markerValues["enum"][0]
+	}
+
+	assert.Equal(t, map[string]interface{}{
+		"Fiction": testEnumMarker{Label: "Fiction"},
+		"Poetry":  testEnumMarker{Label: "Poetry"},
+		"Drama":   testEnumMarker{Label: "Drama"},
+	}, labels)
+}
+
+func TestCollector_CollectFunctionLiteralSpecMarkersOnce(t *testing.T) {
+	registry := NewRegistry()
+	err := registry.Register("marker:handler", "", ValueLevel|FunctionLiteralLevel, &testHandlerMarker{})
+	assert.Nil(t, err)
+
+	collector := NewCollector(registry)
+	collector.UnmatchedMarkers = true
+
+	pkg := parseTestPackage(t, map[string]string{
+		"handler.go": `package test
+
+// +marker:handler:Path=/books
+// +unknown
+var ListBooks = func() {}
+
+// +marker:handler:Path=/limit
+var Limit = 10
+`,
+	})
+
+	results, err := collector.Collect(pkg)
+	assert.Nil(t, err)
+
+	paths := make(map[string]int)
+
+	for node, markerValues := range results {
+		for _, value := range markerValues["marker:handler"] {
+			path := value.(testHandlerMarker).Path
+			paths[path]++
+
+			if path == "/books" {
+				_, ok := node.(*ast.FuncLit)
+				assert.True(t, ok)
+			} else {
+				_, ok := node.(*ast.ValueSpec)
+				assert.True(t, ok)
+			}
+		}
+	}
+
+	assert.Equal(t, map[string]int{"/books": 1, "/limit": 1}, paths)
+	assert.Len(t, collector.Unmatched(), 1)
+}
+
+func TestCollector_CollectWithDisabledMarkers(t *testing.T) {
+	registry := NewRegistry()
+	err := registry.Register("marker:handler", "", FunctionLiteralLevel, &testHandlerMarker{})
+	assert.Nil(t, err)
+	err = registry.Register("enum", "", ValueLevel, &testEnumMarker{})
+	assert.Nil(t, err)
+
+	countMarkers := func(results map[ast.Node]MarkerValues) map[string]int {
+		counts := make(map[string]int)
+
+		for _, markerValues := range results {
+			for name, values := range markerValues {
+				counts[name] += len(values)
+			}
+		}
+
+		return counts
+	}
+
+	pkg := parseTestPackage(t, map[string]string{
+		"book.go": `package test
+
+// +marker:handler:Path=/books
+var ListBooks = func() {}
+
+const (
+	Fiction = iota // +enum:label=Fiction
+	Poetry         // +enum:label=Poetry
+)
+`,
+	})
+
+	collector := NewCollector(registry)
+	collector.DisabledMarkers = map[string]bool{"enum": true}
+	collector.UnmatchedMarkers = true
+
+	results, err := collector.Collect(pkg)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"marker:handler": 1}, countMarkers(results))
+	assert.Len(t, collector.Unmatched(), 2)
+
+	collector.DisabledMarkers = map[string]bool{"marker:handler": true}
+
+	results, err = collector.Collect(pkg)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"enum": 2}, countMarkers(results))
+	assert.Len(t, collector.Unmatched(), 1)
+}
+
+type testPackageLevelMarker struct {
+}
+
+func TestCollector_CollectWithBuildContext(t *testing.T) {
+	pkgs, err := loadLinuxPackages("./test/package1")
+	assert.Nil(t, err)
+
+	registry := NewRegistry()
+	err = registry.Register("marker:package-level", "", PackageLevel, &testPackageLevelMarker{})
+	assert.Nil(t, err)
+
+	collector := NewCollector(registry)
+	packageMarkers := func() int {
+		markers, err := collector.Collect(pkgs[0])
+		assert.Nil(t, err)
+
+		count := 0
+
+		for node, markerValues := range markers {
+			if _, ok := node.(*ast.File); ok {
+				count += len(markerValues["marker:package-level"])
+			}
+		}
+
+		return count
+	}
+
+	linuxContext := build.Default
+	linuxContext.GOOS = "linux"
+	collector.BuildContext = &linuxContext
+	assert.Equal(t, 2, packageMarkers())
+
+	darwinContext := build.Default
+	darwinContext.GOOS = "darwin"
+	collector.BuildContext = &darwinContext
+	assert.Equal(t, 0, packageMarkers())
+}
+
+func TestCollector_SetTextRewriter(t *testing.T) {
+	pkgs, err := LoadPackages("./test/package2")
+	assert.Nil(t, err)
+
+	registry := NewRegistry()
+	err = registry.Register("marker:route", "", FunctionLiteralLevel, &testHandlerMarker{})
+	assert.Nil(t, err)
+
+	collector := NewCollector(registry)
+	collector.SetTextRewriter(func(text string) string {
+		return strings.Replace(text, "+marker:handler:", "+marker:route:", 1)
+	})
+
+	markers, err := collector.Collect(pkgs[0])
+	assert.Nil(t, err)
+
+	var paths []string
+
+	for node, markerValues := range markers {
+		if _, ok := node.(*ast.FuncLit); ok {
+			paths = append(paths, markerValues.Get("marker:route").(testHandlerMarker).Path)
+		}
+	}
+
+	assert.ElementsMatch(t, []string{"/books", "/authors", "/health"}, paths)
+}
+
+func TestFindNodesWithMarker(t *testing.T) {
+	pkgs, err := LoadPackages("./test/package2")
+	assert.Nil(t, err)
+
+	registry := NewRegistry()
+	err = registry.Register("marker:handler", "", FunctionLiteralLevel, &testHandlerMarker{})
+	assert.Nil(t, err)
+
+	markers, err := NewCollector(registry).Collect(pkgs[0])
+	assert.Nil(t, err)
+
+	markedNodes := FindNodesWithMarker(markers, "marker:handler")
+	assert.Len(t, markedNodes, 3)
+
+	var paths []string
+
+	for _, markedNode := range markedNodes {
+		_, ok := markedNode.Node.(*ast.FuncLit)
+		assert.True(t, ok)
+		paths = append(paths, markedNode.Values[0].(testHandlerMarker).Path)
+	}
+
+	assert.Equal(t, []string{"/books", "/authors", "/health"}, paths)
+	assert.Empty(t, FindNodesWithMarker(markers, "marker:unknown"))
+}
+
+func TestCollector_CollectBuildConstraints(t *testing.T) {
+	pkgs, err := loadLinuxPackages("./test/package1")
+	assert.Nil(t, err)
+
+	registry := NewRegistry()
+	err = registry.Register("marker:package-level", "", PackageLevel, &testPackageLevelMarker{})
+	assert.Nil(t, err)
+
+	collector := NewCollector(registry)
+	collector.BuildConstraintMarkers = true
+
+	markers, err := collector.Collect(pkgs[0])
+	assert.Nil(t, err)
+
+	var constraints []BuildConstraint
+
+	for node, markerValues := range markers {
+		if _, ok := node.(*ast.File); ok {
+			assert.Len(t, markerValues["marker:package-level"], 2)
+
+			for _, value := range markerValues[BuildConstraintMarkerName] {
+				constraints = append(constraints, value.(BuildConstraint))
+			}
+		}
+	}
+
+	assert.Len(t, constraints, 1)
+	assert.Equal(t, "linux", constraints[0].Text)
+	assert.True(t, constraints[0].Expr.Eval(func(tag string) bool {
+		return tag == "linux"
+	}))
+	assert.False(t, constraints[0].Expr.Eval(func(tag string) bool {
+		return tag == "darwin"
+	}))
+
+	collector.BuildConstraintMarkers = false
+
+	markers, err = collector.Collect(pkgs[0])
+	assert.Nil(t, err)
+
+	for _, markerValues := range markers {
+		assert.Nil(t, markerValues[BuildConstraintMarkerName])
+	}
+}
+
+type testStrictHandlerMarker struct {
+	Path string `marker:"Path"`
+}
+
+func (m testStrictHandlerMarker) Validate() error {
+	if m.Path == "/health" {
+		return errors.New("'/health' path is reserved")
+	}
+
+	return nil
+}
+
+func TestCollector_Validate(t *testing.T) {
+	pkgs, err := LoadPackages("./test/package2")
+	assert.Nil(t, err)
+
+	registry := NewRegistry()
+	err = registry.Register("marker:handler", "", FunctionLiteralLevel, &testHandlerMarker{})
+	assert.Nil(t, err)
+	assert.Nil(t, NewCollector(registry).Validate(pkgs[0]))
+
+	registry = NewRegistry()
+	err = registry.Register("marker:handler", "", FunctionLiteralLevel, &testStrictHandlerMarker{})
+	assert.Nil(t, err)
+
+	err = NewCollector(registry).Validate(pkgs[0])
+	assert.NotNil(t, err)
+
+	errorList, ok := err.(ErrorList)
+	assert.True(t, ok)
+	assert.Len(t, errorList, 1)
+
+	parserError, ok := errorList[0].(ParserError)
+	assert.True(t, ok)
+	assert.Equal(t, "'/health' path is reserved", parserError.Error())
+	assert.Equal(t, 23, parserError.Position.Line())
+}
+
+func TestCollector_CollectWithHandler(t *testing.T) {
+	pkgs, err := LoadPackages("./test/package2")
+	assert.Nil(t, err)
+
+	registry := NewRegistry()
+	err = registry.Register("marker:handler", "", FunctionLiteralLevel, &testStrictHandlerMarker{})
+	assert.Nil(t, err)
+
+	collector := NewCollector(registry)
+
+	var handledErrors []error
+	markers, err := collector.CollectWithHandler(pkgs[0], func(err error) {
+		handledErrors = append(handledErrors, err)
+	})
+	assert.Nil(t, markers)
+	assert.NotNil(t, err)
+
+	errorList, ok := err.(ErrorList)
+	assert.True(t, ok)
+	assert.Equal(t, []error(errorList), handledErrors)
+
+	parserError, ok := handledErrors[0].(ParserError)
+	assert.True(t, ok)
+	assert.Equal(t, "'/health' path is reserved", parserError.Error())
+	assert.Equal(t, 23, parserError.Position.Line())
+
+	_, err = collector.Collect(pkgs[0])
+	assert.NotNil(t, err)
+	assert.Len(t, handledErrors, 1)
+}
+
+type testTargetNameMarker struct {
+	Name   string `marker:"Name"`
+	Target string `marker:",targetName"`
+}
+
+func TestCollector_CollectTargetName(t *testing.T) {
+	pkgs, err := LoadPackages("./test/package2")
+	assert.Nil(t, err)
+
+	registry := NewRegistry()
+	err = registry.Register("marker:field-level", "", FieldLevel, &testTargetNameMarker{})
+	assert.Nil(t, err)
+
+	collector := NewCollector(registry)
+	collector.StructTagMarkers = true
+
+	markers, err := collector.Collect(pkgs[0])
+	assert.Nil(t, err)
+
+	values := fieldMarkers(markers)
+	assert.Len(t, values, 1)
+	assert.Equal(t, testTargetNameMarker{Name: "title", Target: "Title"}, values[0].Get("marker:field-level"))
+}
+
+func TestGetNodeName(t *testing.T) {
+	file, err := parser.ParseFile(token.NewFileSet(), "test.go", `package test
+
+type Book struct {
+	Title string
+	*Author
+	io.Reader
+}
+
+func Print() {
+}
+`, 0)
+	assert.Nil(t, err)
+
+	typeSpec := file.Decls[0].(*ast.GenDecl).Specs[0].(*ast.TypeSpec)
+	assert.Equal(t, "Book", getNodeName(typeSpec))
+
+	fields := typeSpec.Type.(*ast.StructType).Fields.List
+	assert.Equal(t, "Title", getNodeName(fields[0]))
+	assert.Equal(t, "Author", getNodeName(fields[1]))
+	assert.Equal(t, "Reader", getNodeName(fields[2]))
+
+	assert.Equal(t, "Print", getNodeName(file.Decls[1]))
+	assert.Equal(t, "", getNodeName(file))
+}
+
+func TestCollector_CollectTestFiles(t *testing.T) {
+	pkgs, err := LoadPackagesWithConfig(&packages.Config{Tests: true}, "./test/package2")
+	assert.Nil(t, err)
+
+	var testPackage *Package
+
+	for _, pkg := range pkgs {
+		if len(pkg.Syntax) == 2 {
+			testPackage = pkg
+		}
+	}
+
+	assert.NotNil(t, testPackage)
+
+	registry := NewRegistry()
+	err = registry.Register("marker:handler", "", FunctionLiteralLevel, &testHandlerMarker{})
+	assert.Nil(t, err)
+
+	collector := NewCollector(registry)
+	paths := func() []string {
+		markers, err := collector.Collect(testPackage)
+		assert.Nil(t, err)
+
+		var paths []string
+
+		for _, markedNode := range FindNodesWithMarker(markers, "marker:handler") {
+			paths = append(paths, markedNode.Values[0].(testHandlerMarker).Path)
+		}
+
+		return paths
+	}
+
+	assert.Equal(t, []string{"/books", "/authors", "/health", "/test"}, paths())
+
+	collector.TestFiles = ExcludeTestFiles
+	assert.Equal(t, []string{"/books", "/authors", "/health"}, paths())
+
+	collector.TestFiles = OnlyTestFiles
+	assert.Equal(t, []string{"/test"}, paths())
+}
+
+func TestExpandVariables(t *testing.T) {
+	variables := map[string]string{
+		"VERSION":  "1.2.0",
+		"REGISTRY": "docker.io",
+		"EMPTY":    "",
+	}
+
+	testCases := []struct {
+		Text     string
+		Expected string
+		Error    string
+	}{
+		{Text: "+image:tag=$VERSION", Expected: "+image:tag=1.2.0"},
+		{Text: "+image:name=${REGISTRY}/app,tag=v${VERSION}", Expected: "+image:name=docker.io/app,tag=v1.2.0"},
+		{Text: "+image:tag=${TAG:-latest}", Expected: "+image:tag=latest"},
+		{Text: "+image:tag=${VERSION:-latest}", Expected: "+image:tag=1.2.0"},
+		{Text: "+image:tag=${EMPTY:-latest}", Expected: "+image:tag=latest"},
+		{Text: "+image:tag=${EMPTY}", Expected: "+image:tag="},
+		{Text: "+image:tag=\"$VERSION\",name=`${REGISTRY}`", Expected: "+image:tag=\"$VERSION\",name=`${REGISTRY}`"},
+		{Text: "+image:tag=\"a\\\"$VERSION\",name=$REGISTRY", Expected: "+image:tag=\"a\\\"$VERSION\",name=docker.io"},
+		{Text: "+price:value=$$10,currency=$", Expected: "+price:value=$10,currency=$"},
+		{Text: "+image:tag=$TAG", Error: "variable \"TAG\" cannot be resolved"},
+		{Text: "+image:tag=${TAG}", Error: "variable \"TAG\" cannot be resolved"},
+		{Text: "+image:tag=${VERSION", Error: "variable reference \"${VERSION\" is not closed"},
+		{Text: "+image:tag=${1VERSION}", Error: "variable name \"1VERSION\" is not valid"},
+	}
+
+	for _, testCase := range testCases {
+		text, err := expandVariables(testCase.Text, variables)
+
+		if testCase.Error != "" {
+			assert.NotNil(t, err, testCase.Text)
+			assert.Equal(t, testCase.Error, err.Error(), testCase.Text)
+			continue
+		}
+
+		assert.Nil(t, err, testCase.Text)
+		assert.Equal(t, testCase.Expected, text, testCase.Text)
+	}
+}
+
+type testImageMarker struct {
+	Name string `marker:"name"`
+	Tag  string `marker:"tag"`
+}
+
+func TestCollector_CollectWithVariables(t *testing.T) {
+	registry := NewRegistry()
+	err := registry.Register("image", "", TypeLevel, &testImageMarker{})
+	assert.Nil(t, err)
+
+	collector := NewCollector(registry)
+	collector.Variables = map[string]string{"VERSION": ""}
+
+	pkg := parseTestPackage(t, map[string]string{
+		"book.go": `package test
+
+// +image:name="$REGISTRY/app", tag=${VERSION:-latest}
+type Book struct {}
+`,
+	})
+
+	results, err := collector.Collect(pkg)
+	assert.Nil(t, err)
+	assert.Len(t, results, 1)
+
+	for _, markerValues := range results {
+		assert.Equal(t, testImageMarker{Name: "$REGISTRY/app", Tag: "latest"}, markerValues.Get("image"))
+	}
+}
+
+type testMockNameMarker struct {
+	Name string `marker:"name"`
+}
+
+func TestResolveType(t *testing.T) {
+	registry := NewRegistry()
+	err := registry.Register("mockgen", "", InterfaceTypeLevel, &testMockNameMarker{})
+	assert.Nil(t, err)
+
+	pkg := parseTestPackage(t, map[string]string{
+		"book.go": `package test
+
+type Book struct {
+	Title string
+}
+
+// BookRepository stores the books.
+// +mockgen:name=MockBookRepository
+type BookRepository interface {
+	FindByTitle(title string) (*Book, error)
+}
+`,
+	})
+
+	results, err := NewCollector(registry).Collect(pkg)
+	assert.Nil(t, err)
+
+	node, markerValues, ok := ResolveType(pkg, results, "BookRepository")
+	assert.True(t, ok)
+	assert.Equal(t, "BookRepository", node.(*ast.TypeSpec).Name.Name)
+	assert.Equal(t, testMockNameMarker{Name: "MockBookRepository"}, markerValues.Get("mockgen"))
+
+	node, markerValues, ok = ResolveType(pkg, results, "Book")
+	assert.True(t, ok)
+	assert.Equal(t, "Book", node.(*ast.TypeSpec).Name.Name)
+	assert.Nil(t, markerValues)
+
+	node, markerValues, ok = ResolveType(pkg, results, "Author")
+	assert.False(t, ok)
+	assert.Nil(t, node)
+	assert.Nil(t, markerValues)
+}
+
+func TestCollector_Parents(t *testing.T) {
+	pkgs, err := LoadPackages("./test/package2")
+	assert.Nil(t, err)
+
+	registry := NewRegistry()
+	err = registry.Register("marker:handler", "", FunctionLiteralLevel, &testHandlerMarker{})
+	assert.Nil(t, err)
+
+	collector := NewCollector(registry)
+
+	markers, err := collector.Collect(pkgs[0])
+	assert.Nil(t, err)
+	assert.Nil(t, collector.Parents())
+
+	collector.RecordParents = true
+
+	markers, err = collector.Collect(pkgs[0])
+	assert.Nil(t, err)
+
+	parents := collector.Parents()
+	markedNodes := FindNodesWithMarker(markers, "marker:handler")
+	assert.Len(t, markedNodes, 3)
+
+	for _, markedNode := range markedNodes {
+		parent, ok := parents[markedNode.Node]
+		assert.True(t, ok)
+
+		switch typedParent := parent.(type) {
+		case *ast.FuncDecl:
+			assert.Equal(t, "RegisterHandlers", typedParent.Name.Name)
+		case *ast.GenDecl:
+			assert.Equal(t, token.VAR, typedParent.Tok)
+		default:
+			t.Errorf("unexpected parent %T", parent)
+		}
+	}
+
+	file := pkgs[0].Syntax[0]
+	bookSpec := file.Decls[0].(*ast.GenDecl).Specs[0].(*ast.TypeSpec)
+	titleField := bookSpec.Type.(*ast.StructType).Fields.List[0]
+	assert.Equal(t, bookSpec, parents[titleField])
+	assert.Equal(t, file.Decls[0], parents[bookSpec])
+	assert.Equal(t, file, parents[file.Decls[0]])
+
+	_, ok := parents[file]
+	assert.False(t, ok)
+}
+
+// loadLinuxPackages loads the given packages for linux whatever the host system is,
+// so that the files constrained to other systems are excluded on every host.
+func loadLinuxPackages(patterns ...string) ([]*Package, error) {
+	return LoadPackagesWithConfig(&packages.Config{Env: append(os.Environ(), "GOOS=linux")}, patterns...)
+}
+
+func parseTestPackage(t *testing.T, sources map[string]string) *Package {
+	fset := token.NewFileSet()
+	files := make([]*ast.File, 0, len(sources))
+
+	for fileName, source := range sources {
+		file, err := parser.ParseFile(fset, fileName, source, parser.ParseComments)
+		assert.Nil(t, err)
+		files = append(files, file)
+	}
+
+	return &Package{
+		Package: &packages.Package{
+			Name:   "test",
+			Fset:   fset,
+			Syntax: files,
+		},
+	}
+}
+
+func TestCollector_CollectImportConflicts(t *testing.T) {
+	collector := NewCollector(NewRegistry())
+
+	pkg := parseTestPackage(t, map[string]string{
+		"first.go": `package test
+
+// +import=marker, Pkg="github.com/procyon-projects/marker@1.2.4:command"
+import "fmt"
+`,
+		"second.go": `package test
+
+// +import=marker, Pkg="github.com/procyon-projects/marker@1.2.4:command"
+import "fmt"
+`,
+	})
+
+	_, err := collector.Collect(pkg)
+	assert.Nil(t, err)
+
+	pkg = parseTestPackage(t, map[string]string{
+		"first.go": `package test
+
+// +import=marker, Pkg="github.com/procyon-projects/marker@1.2.4:command"
+// +import=other, Pkg="github.com/procyon-projects/marker@1.2.4:command"
+import "fmt"
+`,
+	})
+
+	_, err = collector.Collect(pkg)
+	assert.NotNil(t, err)
+	assert.Equal(t, "processor with Pkg 'github.com/procyon-projects/marker' has already been imported at first.go:3:1", err.(ErrorList)[0].Error())
+	assert.Equal(t, 4, err.(ErrorList)[0].(ParserError).Position.Line())
+
+	pkg = parseTestPackage(t, map[string]string{
+		"first.go": `package test
+
+// +import=marker, Pkg="github.com/procyon-projects/marker@1.2.4:command"
+import "fmt"
+`,
+		"second.go": `package test
+
+import "strings"
+
+// +import=marker, Alias=m, Pkg="github.com/procyon-projects/marker@1.2.4:command"
+import "fmt"
+`,
+	})
+
+	_, err = collector.Collect(pkg)
+	assert.NotNil(t, err)
+
+	parserError := err.(ErrorList)[0].(ParserError)
+	assert.Equal(t, "processor with Pkg 'github.com/procyon-projects/marker' is imported as 'm', but it has already been imported as 'marker' at first.go:3:1", parserError.Error())
+	assert.Equal(t, "second.go", parserError.Position.File())
+	assert.Equal(t, 5, parserError.Position.Line())
+}
+
+func TestCollector_AddPostProcessor(t *testing.T) {
+	pkgs, err := LoadPackages("./test/package2")
+	assert.Nil(t, err)
+
+	registry := NewRegistry()
+	err = registry.Register("marker:handler", "", FunctionLiteralLevel, &testHandlerMarker{})
+	assert.Nil(t, err)
+	err = registry.Register("enum", "", ValueLevel, &testEnumMarker{})
+	assert.Nil(t, err)
+
+	collector := NewCollector(registry)
+
+	var calls []string
+
+	collector.AddPostProcessor(func(markers map[ast.Node]MarkerValues) error {
+		calls = append(calls, "remove")
+
+		for node, markerValues := range markers {
+			delete(markerValues, "enum")
+
+			if len(markerValues) == 0 {
+				delete(markers, node)
+			}
+		}
+
+		return nil
+	})
+
+	collector.AddPostProcessor(func(markers map[ast.Node]MarkerValues) error {
+		calls = append(calls, "check")
+
+		for _, markerValues := range markers {
+			assert.NotContains(t, markerValues, "enum")
+		}
+
+		return nil
+	})
+
+	results, err := collector.Collect(pkgs[0])
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"remove", "check"}, calls)
+	assert.Len(t, results, 3)
+
+	collector.AddPostProcessor(func(markers map[ast.Node]MarkerValues) error {
+		return errors.New("post processor error")
+	})
+
+	results, err = collector.Collect(pkgs[0])
+	assert.Nil(t, results)
+	assert.Equal(t, "post processor error", err.Error())
+}
+
+func TestReceiverTypeName(t *testing.T) {
+	file, err := parser.ParseFile(token.NewFileSet(), "test.go", `package test
+
+type Book struct{}
+type List[T any] struct{}
+
+func (book Book) Title() string { return "" }
+func (book *Book) SetTitle(title string) {}
+func (*Book) Reset() {}
+func (list *List[T]) Add(item T) {}
+func NewBook() *Book { return nil }
+`, parser.ParseComments)
+	assert.Nil(t, err)
+
+	receiverTypes := make(map[string]string)
+
+	for _, decl := range file.Decls {
+		if funcDecl, ok := decl.(*ast.FuncDecl); ok {
+			receiverTypes[funcDecl.Name.Name] = ReceiverTypeName(funcDecl)
+			assert.Equal(t, receiverTypes[funcDecl.Name.Name], Method{RawFuncDecl: funcDecl}.ReceiverType())
+		}
+	}
+
+	assert.Equal(t, map[string]string{
+		"Title":    "Book",
+		"SetTitle": "Book",
+		"Reset":    "Book",
+		"Add":      "List",
+		"NewBook":  "",
+	}, receiverTypes)
+	assert.Equal(t, "", Method{}.ReceiverType())
+}
+
+type testLimitMarker struct {
+	Min int `marker:"min"`
+	Max int `marker:"max"`
+}
+
+type testSummaryMarker struct {
+	Text  string           `marker:"text"`
+	Max   int              `marker:"max,optional,reference"`
+	Limit *testLimitMarker `marker:"limit,optional,reference"`
+}
+
+func TestCollector_CollectMarkerReferences(t *testing.T) {
+	registry := NewRegistry()
+	err := registry.Register("limit", "", TypeLevel, &testLimitMarker{})
+	assert.Nil(t, err)
+	err = registry.Register("summary", "", TypeLevel, &testSummaryMarker{})
+	assert.Nil(t, err)
+
+	collector := NewCollector(registry)
+
+	pkg := parseTestPackage(t, map[string]string{
+		"book.go": `package test
+
+// +summary:text="pages", max=@limit.max
+// +limit:min=1, max=500
+type Book struct {}
+
+// +limit:min=1, max=10
+// +summary:text="copies", limit=@limit
+type Copy struct {}
+`,
+	})
+
+	results, err := collector.Collect(pkg)
+	assert.Nil(t, err)
+
+	summaries := make(map[string]testSummaryMarker)
+
+	for _, markerValues := range results {
+		summary := markerValues.Get("summary").(testSummaryMarker)
+		summaries[summary.Text] = summary
+	}
+
+	assert.Equal(t, testSummaryMarker{Text: "pages", Max: 500}, summaries["pages"])
+	assert.Equal(t, testSummaryMarker{Text: "copies", Limit: &testLimitMarker{Min: 1, Max: 10}}, summaries["copies"])
+
+	pkg = parseTestPackage(t, map[string]string{
+		"book.go": `package test
+
+// +summary:text="pages", max=@limit.max
+type Book struct {}
+`,
+	})
+
+	_, err = collector.Collect(pkg)
+	assert.NotNil(t, err)
+
+	parserError := err.(ErrorList)[0].(ParserError)
+	assert.Contains(t, parserError.Error(), "marker reference \"@limit.max\" cannot be resolved, there is no such marker on the node")
+	assert.Equal(t, 3, parserError.Position.Line())
+
+	definition := registry.Lookup("+summary", "")
+	_, err = definition.Parse(`+summary:text="pages", max=@limit.max`)
+	assert.NotNil(t, err)
+}
+
+func TestCollector_CollectIncremental(t *testing.T) {
+	pkgs, err := LoadPackages("./test/package2")
+	assert.Nil(t, err)
+
+	registry := NewRegistry()
+	err = registry.Register("marker:handler", "", FunctionLiteralLevel, &testHandlerMarker{})
+	assert.Nil(t, err)
+
+	collector := NewCollector(registry)
+	expected, err := collector.Collect(pkgs[0])
+	assert.Nil(t, err)
+
+	results, index, err := collector.CollectIncremental(pkgs[0], nil)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, results)
+	assert.Equal(t, len(pkgs[0].Syntax), index.Len())
+
+	file := pkgs[0].Syntax[0]
+	fileComments := index.files[file]
+
+	results, index, err = collector.CollectIncremental(pkgs[0], index)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, results)
+	assert.Same(t, fileComments, index.files[file])
+
+	// the files which are not in the package anymore are removed from the index
+	results, index, err = collector.CollectIncremental(&Package{Package: &packages.Package{
+		Fset:   pkgs[0].Fset,
+		Syntax: []*ast.File{},
+	}}, index)
+	assert.Nil(t, err)
+	assert.Empty(t, results)
+	assert.Equal(t, 0, index.Len())
+}
+
+func BenchmarkCollector_Collect(b *testing.B) {
+	pkgs, _ := LoadPackages("./test/package2")
+	registry := NewRegistry()
+	_ = registry.Register("marker:handler", "", FunctionLiteralLevel, &testHandlerMarker{})
+	collector := NewCollector(registry)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, _ = collector.Collect(pkgs[0])
+	}
+}
+
+func BenchmarkCollector_CollectIncremental(b *testing.B) {
+	pkgs, _ := LoadPackages("./test/package2")
+	registry := NewRegistry()
+	_ = registry.Register("marker:handler", "", FunctionLiteralLevel, &testHandlerMarker{})
+	collector := NewCollector(registry)
+	_, index, _ := collector.CollectIncremental(pkgs[0], nil)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, index, _ = collector.CollectIncremental(pkgs[0], index)
+	}
+}
+
+func TestCollector_CollectMarkerRange(t *testing.T) {
+	registry := NewRegistry()
+	err := registry.Register("limit", "", TypeLevel, &testLimitMarker{})
+	assert.Nil(t, err)
+
+	collector := NewCollector(registry)
+	collector.UnmatchedMarkers = true
+
+	pkg := parseTestPackage(t, map[string]string{
+		"book.go": `package test
+
+// +limit:min=1, \
+//  max=ten
+// +unknown:name=test
+type Book struct {}
+`,
+	})
+
+	_, err = collector.Collect(pkg)
+	assert.NotNil(t, err)
+
+	parserError := err.(ErrorList)[0].(ErrorList)[0].(ParserError)
+	assert.Equal(t, 3, parserError.Position.Line())
+	assert.Equal(t, 1, parserError.Position.Column())
+	assert.Equal(t, 4, parserError.End.Line())
+	assert.Equal(t, 12, parserError.End.Column())
+
+	unmatched := collector.Unmatched()
+	assert.Len(t, unmatched, 1)
+	assert.Equal(t, 5, unmatched[0].Position.Line)
+	assert.Equal(t, 5, unmatched[0].End.Line)
+	assert.Equal(t, 22, unmatched[0].End.Column)
+	assert.Equal(t, "+unknown:name=test", unmatched[0].Text)
+	assert.Equal(t, "// +unknown:name=test", unmatched[0].RawText)
+}
+
+func TestCollector_CollectAll(t *testing.T) {
+	pkgs, err := LoadPackages("./test/package2", "./test/testdata/package3")
+	assert.Nil(t, err)
+	assert.Len(t, pkgs, 2)
+
+	registry := NewRegistry()
+	err = registry.Register("marker:handler", "", FunctionLiteralLevel, &testHandlerMarker{})
+	assert.Nil(t, err)
+
+	collector := NewCollector(registry)
+	collector.UnmatchedMarkers = true
+
+	markers, err := collector.CollectAll(pkgs)
+	assert.Nil(t, err)
+
+	var paths []string
+
+	for _, markerValues := range markers {
+		if handler := markerValues.Get("marker:handler"); handler != nil {
+			paths = append(paths, handler.(testHandlerMarker).Path)
+		}
+	}
+
+	assert.ElementsMatch(t, []string{"/books", "/authors", "/health", "/orders"}, paths)
+
+	// the unmatched markers of all the packages are kept
+	unmatched := collector.Unmatched()
+	assert.Len(t, unmatched, 2)
+	assert.Equal(t, "+deprecated Use Name instead", unmatched[0].Text)
+	assert.Equal(t, "+marker:package-level", unmatched[1].Text)
+
+	err = registry.Register("limit", "", TypeLevel, &testLimitMarker{})
+	assert.Nil(t, err)
+
+	_, err = collector.CollectAll([]*Package{
+		parseTestPackage(t, map[string]string{
+			"first.go": `package test
+
+// +limit:min=one, max=10
+type First struct {}
+`,
+		}),
+		parseTestPackage(t, map[string]string{
+			"second.go": `package test
+
+// +limit:min=1, max=ten
+type Second struct {}
+`,
+		}),
+	})
+	assert.NotNil(t, err)
+	assert.Len(t, err.(ErrorList), 2)
+	assert.Equal(t, "first.go", err.(ErrorList)[0].(ErrorList)[0].(ParserError).FileName)
+	assert.Equal(t, "second.go", err.(ErrorList)[1].(ErrorList)[0].(ParserError).FileName)
+}
+
+type testArticleMarker struct {
+	Title  string `marker:"title"`
+	Name   string `marker:"name,deprecated=use title"`
+	Author string `marker:"author,deprecated=authors are not supported anymore"`
+}
+
+func TestCollector_CollectDeprecatedArguments(t *testing.T) {
+	registry := NewRegistry()
+	err := registry.Register("article", "", TypeLevel, &testArticleMarker{})
+	assert.Nil(t, err)
+
+	collector := NewCollector(registry)
+
+	pkg := parseTestPackage(t, map[string]string{
+		"article.go": `package test
+
+// +article:name=Intro, author=Jane
+type First struct {}
+
+// +article:title=Guide, name=Other
+type Second struct {}
+
+// +article:title=Reference
+type Third struct {}
+`,
+	})
+
+	results, err := collector.Collect(pkg)
+	assert.Nil(t, err)
+
+	articles := make(map[string]testArticleMarker)
+
+	for node, markerValues := range results {
+		articles[getNodeName(node)] = markerValues.Get("article").(testArticleMarker)
+	}
+
+	assert.Equal(t, testArticleMarker{Title: "Intro", Name: "Intro", Author: "Jane"}, articles["First"])
+	assert.Equal(t, testArticleMarker{Title: "Guide", Name: "Other"}, articles["Second"])
+	assert.Equal(t, testArticleMarker{Title: "Reference"}, articles["Third"])
+
+	warnings := collector.Warnings()
+	sort.Slice(warnings, func(i, j int) bool {
+		if warnings[i].Position.Line != warnings[j].Position.Line {
+			return warnings[i].Position.Line < warnings[j].Position.Line
+		}
+
+		return warnings[i].Message < warnings[j].Message
+	})
+
+	assert.Len(t, warnings, 3)
+	assert.Equal(t, "argument \"author\" of marker \"+article\" is deprecated: authors are not supported anymore", warnings[0].Message)
+	assert.Equal(t, 3, warnings[0].Position.Line)
+	assert.Equal(t, "argument \"name\" of marker \"+article\" is deprecated: use title", warnings[1].Message)
+	assert.Equal(t, 3, warnings[1].Position.Line)
+	assert.Equal(t, "argument \"name\" of marker \"+article\" is deprecated: use title", warnings[2].Message)
+	assert.Equal(t, 6, warnings[2].Position.Line)
+
+	_, err = MakeDefinition("article", "", TypeLevel, &struct {
+		Title string `marker:"title"`
+		Name  int    `marker:"name,deprecated=use title"`
+	}{})
+	assert.NotNil(t, err)
+	assert.Equal(t, "'name' field is deprecated in favor of \"title\", but their types are different", err.Error())
+}
+
+type testQuotaMarker struct {
+	Max   int     `marker:"max"`
+	Ratio float64 `marker:"ratio,optional"`
+}
+
+func TestCollector_CollectConstantValues(t *testing.T) {
+	registry := NewRegistry()
+	err := registry.Register("quota", "", TypeLevel, &testQuotaMarker{})
+	assert.Nil(t, err)
+
+	collector := NewCollector(registry)
+
+	typeCheckedPackage := func(source string) *Package {
+		pkg := parseTestPackage(t, map[string]string{"quota.go": source})
+		pkg.Types, err = (&types.Config{}).Check("test", pkg.Fset, pkg.Syntax, nil)
+		assert.Nil(t, err)
+		return pkg
+	}
+
+	pkg := typeCheckedPackage(`package test
+
+const MaxRequests = 1 << 10
+
+const Ratio = 0.75
+
+// +quota:max=MaxRequests, ratio=Ratio
+type First struct {}
+
+// +quota:max=-MaxRequests, ratio=-Ratio
+type Second struct {}
+`)
+
+	results, err := collector.Collect(pkg)
+	assert.Nil(t, err)
+
+	quotas := make(map[string]testQuotaMarker)
+
+	for node, markerValues := range results {
+		quotas[getNodeName(node)] = markerValues.Get("quota").(testQuotaMarker)
+	}
+
+	assert.Equal(t, testQuotaMarker{Max: 1024, Ratio: 0.75}, quotas["First"])
+	assert.Equal(t, testQuotaMarker{Max: -1024, Ratio: -0.75}, quotas["Second"])
+
+	pkg = typeCheckedPackage(`package test
+
+const Name = "quota"
+
+// +quota:max=MaxRequests
+type First struct {}
+
+// +quota:max=Name
+type Second struct {}
+`)
+
+	_, err = collector.Collect(pkg)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "unable to resolve constant \"MaxRequests\"")
+	assert.Contains(t, err.Error(), "constant \"Name\" is not an integer")
+
+	// the constants cannot be resolved without the type information
+	pkg.Types = nil
+
+	_, err = collector.Collect(pkg)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "got \"MaxRequests\"; want Integer")
+}
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -20,6 +22,25 @@ type Output struct {
 	AnonymousTypeInfo ArgumentTypeInfo
 	Fields            map[string]Argument
 	FieldNames        map[string]string
+	// TargetNameField is the name of the field filled with the name of the node
+	// which the marker is associated with.
+	TargetNameField string
+	// Positional makes the arguments be given without their names in the declaration
+	// order of the fields such as '+size:10,20'. The arguments can still be given with
+	// their names, but the positional and the named arguments cannot be mixed in a marker.
+	Positional bool
+	// Raw indicates that the whole text after the marker name is captured into the 'Value'
+	// field without any tokenization, unlike the syntax-free markers the text keeps its
+	// commas, quotes and whitespaces as they are.
+	Raw bool
+	// Strict makes the unknown arguments be reported as errors such as 'unknown argument
+	// "maxx" for marker "+validation"' instead of being ignored, unless there is a rest field.
+	Strict bool
+	// RestField is the name of the field filled with the unknown arguments.
+	RestField string
+	// PreferFloat makes the integers in the values of interface{} arguments be parsed as
+	// float64 instead of int, such as the values which are serialized into JSON.
+	PreferFloat bool
 }
 
 type Definition struct {
@@ -27,6 +48,13 @@ type Definition struct {
 	Level  TargetLevel
 	Output Output
 	PkgId  string
+	// Defaults is called with a pointer to the new output value before the arguments are
+	// parsed, so that it can set the defaults which are too complex for the struct tags.
+	// The arguments given in the marker override them.
+	Defaults func(out interface{})
+	// Metadata is the additional information of the marker for the tools such as its
+	// category, its help text or its documentation URL. It is not used by the collector.
+	Metadata map[string]string
 }
 
 func MakeDefinition(name string, pkgId string, level TargetLevel, output interface{}) (*Definition, error) {
@@ -61,6 +89,121 @@ func MakeDefinition(name string, pkgId string, level TargetLevel, output interfa
 	return definition, nil
 }
 
+// GetMetadata returns the metadata value of the given key, and whether the key exists.
+func (definition *Definition) GetMetadata(key string) (string, bool) {
+	value, exists := definition.Metadata[key]
+	return value, exists
+}
+
+// Levels returns the individual target levels which the definition can be applied to.
+func (definition *Definition) Levels() []TargetLevel {
+	levels := make([]TargetLevel, 0)
+
+	for level := PackageLevel; level <= ValueLevel; level <<= 1 {
+		if definition.Level&level == level {
+			levels = append(levels, level)
+		}
+	}
+
+	return levels
+}
+
+// setTargetName sets the target name field of the given parsed output to the given name
+// if the definition has a target name field, and returns the output.
+func (definition *Definition) setTargetName(value interface{}, name string) interface{} {
+	if definition.Output.TargetNameField == "" || value == nil {
+		return value
+	}
+
+	output := reflect.New(reflect.TypeOf(value)).Elem()
+	output.Set(reflect.ValueOf(value))
+
+	fieldValue := output.FieldByName(definition.Output.TargetNameField)
+	(ArgumentTypeInfo{}).setValue(fieldValue, reflect.ValueOf(name))
+
+	return output.Interface()
+}
+
+// ArgumentSignature returns the expected arguments of the definition with their
+// types in the order of the output fields such as 'name string, max int (optional)'.
+func (definition *Definition) ArgumentSignature() string {
+	if definition.Output.IsAnonymous {
+		return fmt.Sprintf("%s %v", ValueArgument, definition.Output.Type)
+	}
+
+	arguments := make([]string, 0, len(definition.Output.Fields))
+
+	for index := 0; index < definition.Output.Type.NumField(); index++ {
+		field := definition.Output.Type.Field(index)
+
+		for argumentName, fieldName := range definition.Output.FieldNames {
+			if fieldName != field.Name {
+				continue
+			}
+
+			argument := definition.Output.Fields[argumentName]
+			signature := fmt.Sprintf("%s %v", argumentName, field.Type)
+
+			if !argument.Required {
+				signature = signature + " (optional)"
+			}
+
+			arguments = append(arguments, signature)
+		}
+	}
+
+	return strings.Join(arguments, ", ")
+}
+
+// appendArgumentSignature appends the expected arguments of the definition
+// to the given parse error to make it clear how the marker must be written.
+func (definition *Definition) appendArgumentSignature(err error) error {
+	signature := definition.ArgumentSignature()
+
+	if signature == "" {
+		return err
+	}
+
+	errorList, ok := err.(ErrorList)
+
+	if !ok {
+		errorList = ErrorList{err}
+	}
+
+	return append(errorList[:len(errorList):len(errorList)], ScannerError{
+		Message: fmt.Sprintf("expected arguments: %s", signature),
+	})
+}
+
+// resolveTypes resolves the concrete types of the interface-typed arguments and
+// the custom parsers of the arguments by using the given implementations and parsers.
+func (definition *Definition) resolveTypes(implementations map[reflect.Type]reflect.Type, parsers map[reflect.Type]ArgumentParser) error {
+	if definition.Output.IsAnonymous {
+		return definition.Output.AnonymousTypeInfo.resolveType(implementations, parsers)
+	}
+
+	argumentNames := make([]string, 0, len(definition.Output.Fields))
+
+	for argumentName := range definition.Output.Fields {
+		argumentNames = append(argumentNames, argumentName)
+	}
+
+	sort.Strings(argumentNames)
+
+	for _, argumentName := range argumentNames {
+		argument := definition.Output.Fields[argumentName]
+		err := argument.TypeInfo.resolveType(implementations, parsers)
+
+		if err != nil {
+			return fmt.Errorf("argument %q cannot be resolved : %w", argumentName, err)
+		}
+
+		definition.Output.Fields[argumentName] = argument
+	}
+
+	return nil
+}
+
 func (definition *Definition) extract() error {
 
 	if definition.Output.Type.Kind() != reflect.Struct {
@@ -88,8 +231,18 @@ func (definition *Definition) extract() error {
 			return err
 		}
 
-		if argumentInfo.TypeInfo.ActualType == RawType {
-			return errors.New("RawArgument cannot be a field")
+		// the unknown arguments are collected into the rest field
+		if argumentInfo.Rest && definition.Output.RestField != "" {
+			return fmt.Errorf("output can only have one field with rest option")
+		} else if argumentInfo.Rest {
+			definition.Output.RestField = field.Name
+			continue
+		}
+
+		// the target name is not an argument written in the marker
+		if argumentInfo.TargetName {
+			definition.Output.TargetNameField = field.Name
+			continue
 		}
 
 		if argumentInfo.SyntaxFree {
@@ -100,6 +253,10 @@ func (definition *Definition) extract() error {
 			definition.Output.UseValueSyntax = argumentInfo.UseValueSyntax
 		}
 
+		if argumentInfo.Raw {
+			definition.Output.Raw = argumentInfo.Raw
+		}
+
 		definition.Output.Fields[argumentInfo.Name] = argumentInfo
 		definition.Output.FieldNames[argumentInfo.Name] = field.Name
 	}
@@ -108,32 +265,103 @@ func (definition *Definition) extract() error {
 		return fmt.Errorf("output can only have 'Value' field since syntaxFree option is used")
 	}
 
+	if len(definition.Output.Fields) > 1 && definition.Output.Raw {
+		return fmt.Errorf("output can only have 'Value' field since raw option is used")
+	}
+
+	// the deprecated argument such as 'deprecated=use title' is replaced with the argument
+	for argumentName, argument := range definition.Output.Fields {
+		replacementName := strings.TrimSpace(strings.TrimPrefix(argument.Deprecated, "use "))
+		replacement, ok := definition.Output.Fields[replacementName]
+
+		if !strings.HasPrefix(argument.Deprecated, "use ") || !ok || replacementName == argumentName {
+			continue
+		}
+
+		if replacement.TypeInfo.Type != argument.TypeInfo.Type {
+			return fmt.Errorf("'%s' field is deprecated in favor of %q, but their types are different", argumentName, replacementName)
+		}
+
+		argument.Replacement = replacementName
+		definition.Output.Fields[argumentName] = argument
+	}
+
+	for argumentName, argument := range definition.Output.Fields {
+		if argument.RequiredIf == nil {
+			continue
+		}
+
+		if _, ok := definition.Output.Fields[argument.RequiredIf.Argument]; !ok || argument.RequiredIf.Argument == argumentName {
+			return fmt.Errorf("'%s' field is required if %q, but there is no other argument named %q", argumentName, argument.RequiredIf.String(), argument.RequiredIf.Argument)
+		}
+	}
+
 	return nil
 }
 
 func (definition *Definition) Parse(marker string) (interface{}, error) {
+	value, _, err := definition.ParseWithPresence(marker)
+	return value, err
+}
+
+// ParseWithPresence functions like Parse, and it also returns the names of the arguments
+// which are explicitly set in the marker. It makes it possible to distinguish an omitted
+// argument from an argument set to its zero value such as '+marker:name=""'.
+func (definition *Definition) ParseWithPresence(marker string) (interface{}, map[string]bool, error) {
+	value, seen, references, err := definition.parse(marker, nil)
+
+	if err == nil && len(references) != 0 {
+		return nil, nil, NewErrorList([]error{ScannerError{
+			Message: fmt.Sprintf("marker reference %q can only be resolved by the collector", "@"+references[0].Reference),
+		}})
+	}
+
+	return value, seen, err
+}
+
+// parse functions like ParseWithPresence, and it also returns the marker references such as
+// '@validation' given as the argument values. The arguments of the references are not set.
+// The names of the constants given as the numeric values are resolved by the given resolver
+// if it is not nil.
+func (definition *Definition) parse(marker string, resolveConstant constantResolver) (interface{}, map[string]bool, []markerReference, error) {
 	if definition.Output.SyntaxFree {
-		return definition.parseSyntaxFree(marker), nil
+		return definition.parseSyntaxFree(marker), map[string]bool{ValueArgument: true}, nil, nil
+	}
+
+	if definition.Output.Raw {
+		return definition.parseRaw(marker), map[string]bool{ValueArgument: true}, nil, nil
 	}
 
 	output := reflect.Indirect(reflect.New(definition.Output.Type))
 
-	name, anonymousName, fields := splitMarker(marker)
+	if definition.Defaults != nil {
+		definition.Defaults(output.Addr().Interface())
+	}
 
-	if !definition.Output.UseValueSyntax && len(anonymousName) >= len(name)+1 {
-		fields = anonymousName[len(name)+1:] + "=" + fields
+	_, anonymousName, fields := splitMarker(marker)
+
+	// the text after the definition name is the arguments such as '+name:argument=value'
+	if !definition.Output.UseValueSyntax && strings.HasPrefix(marker, "+"+definition.Name+":") {
+		anonymousName = definition.Name
+		fields = marker[len(definition.Name)+2:]
 	}
 
 	var errs []error
 
-	if strings.ContainsAny(anonymousName, ".,;=") {
+	if strings.ContainsAny(anonymousName, ",;=") {
 		errs = append(errs, ScannerError{
 			Message: fmt.Sprintf("Marker format is not valid : %s", marker),
 		})
-		return nil, NewErrorList(errs)
+		return nil, nil, nil, NewErrorList(errs)
 	}
 
 	scanner := NewScanner(fields)
+	// the fields might be joined from multiple comment lines
+	scanner.SkipContinuations = true
+	scanner.PreferFloat = definition.Output.PreferFloat
+	scanner.resolveConstant = resolveConstant
+	// the arguments are the end of the marker
+	scanner.columnOffset = len(marker) - len(fields)
 	scanner.ErrorCallback = func(scanner *Scanner, message string) {
 		errs = append(errs, ScannerError{
 			Message: message,
@@ -143,14 +371,39 @@ func (definition *Definition) Parse(marker string) (interface{}, error) {
 	valueArgumentProcessed := false
 	canBeValueArgument := false
 
-	seen := make(map[string]struct{}, len(definition.Output.Fields))
+	seen := make(map[string]bool, len(definition.Output.Fields))
+
+	namedCount, positionalCount := 0, 0
 
-	if scanner.Peek() != EOF {
+	if definition.Output.Positional {
+		namedCount, positionalCount = countArguments(fields)
+	}
+
+	if namedCount != 0 && positionalCount != 0 {
+		errs = append(errs, ScannerError{
+			Message: fmt.Sprintf("positional and named arguments cannot be mixed : %s", marker),
+		})
+		return nil, nil, nil, NewErrorList(errs)
+	}
+
+	var references []markerReference
+
+	if positionalCount != 0 {
+		definition.parsePositionalArguments(scanner, output, seen)
+	} else if scanner.Peek() != EOF {
 		for {
 			var argumentName string
 			currentCharacter := scanner.SkipWhitespaces()
 
-			if definition.Output.UseValueSyntax && !valueArgumentProcessed && currentCharacter == '{' || currentCharacter == '"' {
+			// boolean negation such as '!argument' sets the argument to false
+			negated := currentCharacter == '!'
+
+			if negated {
+				scanner.Scan()
+				currentCharacter = scanner.SkipWhitespaces()
+			}
+
+			if !negated && definition.Output.UseValueSyntax && !valueArgumentProcessed && currentCharacter == '{' || currentCharacter == '"' {
 				canBeValueArgument = true
 			} else if definition.Output.UseValueSyntax && !scanner.Expect(Identifier, "Value") {
 				continue
@@ -161,9 +414,10 @@ func (definition *Definition) Parse(marker string) (interface{}, error) {
 			argumentName = scanner.Token()
 			currentCharacter = scanner.SkipWhitespaces()
 
-			if definition.Output.UseValueSyntax && !valueArgumentProcessed && (currentCharacter == EOF || currentCharacter == ',' || currentCharacter == ';') {
+			// there is no value to parse for the negated arguments
+			if !negated && definition.Output.UseValueSyntax && !valueArgumentProcessed && (currentCharacter == EOF || currentCharacter == ',' || currentCharacter == ';') {
 				canBeValueArgument = true
-			} else if (valueArgumentProcessed || !canBeValueArgument) && !scanner.Expect('=', "Equals Sign '='") {
+			} else if !negated && (valueArgumentProcessed || !canBeValueArgument) && !scanner.Expect('=', "Equals Sign '='") {
 				break
 			}
 
@@ -178,37 +432,74 @@ func (definition *Definition) Parse(marker string) (interface{}, error) {
 			var err error
 			var fieldValue reflect.Value
 			var argument Argument
+			var previousValue reflect.Value
 
-			// if the argument name does not exist in field names, parse its value to skip
-			if !exists {
-				var anyValue interface{}
-				(&ArgumentTypeInfo{ActualType: AnyType}).Parse(scanner, reflect.ValueOf(&anyValue))
-				goto nextAttribute
+			if exists {
+				argument, exists = definition.Output.Fields[argumentName]
 			}
 
-			argument, exists = definition.Output.Fields[argumentName]
-
 			// if the argument name does not exist in fields, parse its value to skip
 			if !exists {
-				var anyValue interface{}
-				(&ArgumentTypeInfo{ActualType: AnyType}).Parse(scanner, reflect.ValueOf(&anyValue))
+				if !definition.parseUnknownArgument(scanner, output, argumentName, negated) {
+					break
+				}
 				goto nextAttribute
 			}
 
-			seen[argumentName] = struct{}{}
-
 			fieldValue = output.FieldByName(fieldName)
 
 			if !fieldValue.CanSet() {
 				break
 			}
 
-			err = argument.TypeInfo.Parse(scanner, fieldValue)
+			// the values of a repeated slice argument such as 'tag=a,tag=b' are appended
+			if seen[argumentName] && argument.TypeInfo.ActualType != SliceType {
+				scanner.AddError(fmt.Sprintf("argument %q cannot be given more than once", argumentName))
+				break
+			} else if seen[argumentName] {
+				previousValue = reflect.ValueOf(reflect.Indirect(fieldValue).Interface())
+			}
+
+			seen[argumentName] = true
+
+			if negated && argument.TypeInfo.ActualType != BoolType {
+				scanner.AddError(fmt.Sprintf("'!' can only be used with boolean arguments, %q is not boolean", argumentName))
+				break
+			} else if negated {
+				argument.TypeInfo.setValue(fieldValue, reflect.ValueOf(false))
+				goto nextAttribute
+			}
+
+			// the value such as '@validation' refers to another marker on the same node
+			if argument.Reference && scanner.SkipWhitespaces() == '@' {
+				reference := scanMarkerReference(scanner)
+
+				if reference == "" {
+					scanner.AddError(fmt.Sprintf("argument %q: marker reference is missing the marker name", argumentName))
+					break
+				}
+
+				references = append(references, markerReference{ArgumentName: argumentName, Reference: reference})
+				goto nextAttribute
+			}
+
+			err = parseArgument(argument, scanner, fieldValue)
 
-			if err != nil {
+			if errors.Is(err, strconv.ErrRange) {
+				scanner.AddError(fmt.Sprintf("argument %q: %v", argumentName, err))
+				break
+			} else if err != nil {
+				scanner.AddError(err.Error())
 				break
 			}
 
+			if previousValue.IsValid() {
+				currentValue := reflect.Indirect(fieldValue)
+				currentValue.Set(reflect.AppendSlice(previousValue, currentValue))
+			}
+
+			allocateEmptyCollection(argument, fieldValue)
+
 		nextAttribute:
 			if scanner.Peek() == EOF {
 				break
@@ -219,13 +510,527 @@ func (definition *Definition) Parse(marker string) (interface{}, error) {
 		}
 	}
 
+	// the values of the deprecated arguments are set to their replacements
 	for argumentName, argument := range definition.Output.Fields {
-		if _, wasSeen := seen[argumentName]; !wasSeen && argument.Required {
+		if argument.Replacement == "" || !seen[argumentName] || seen[argument.Replacement] {
+			continue
+		}
+
+		replacementValue := output.FieldByName(definition.Output.FieldNames[argument.Replacement])
+		deprecatedValue := reflect.Indirect(output.FieldByName(definition.Output.FieldNames[argumentName]))
+		definition.Output.Fields[argument.Replacement].TypeInfo.setValue(replacementValue, deprecatedValue)
+		seen[argument.Replacement] = true
+	}
+
+	for argumentName, argument := range definition.Output.Fields {
+		if !seen[argumentName] && argument.Required {
 			scanner.AddError(fmt.Sprintf("missing argument %q", argumentName))
 		}
 	}
 
-	return output.Interface(), NewErrorList(errs)
+	return output.Interface(), seen, references, NewErrorList(errs)
+}
+
+// markerReference is an argument value such as '@validation' or '@validation.max' which
+// refers to the value or an argument of another marker on the same node.
+type markerReference struct {
+	ArgumentName string
+	Reference    string
+}
+
+// scanMarkerReference scans the marker reference starting with '@' and returns it without '@'.
+func scanMarkerReference(scanner *Scanner) string {
+	scanner.Scan()
+
+	start := scanner.SearchIndex()
+	character := scanner.Peek()
+
+	for IsIdentifier(character, 1) || character == ':' || character == '.' || character == '-' {
+		character = scanner.Next()
+	}
+
+	scanner.character = character
+	return string(scanner.source[start:scanner.SearchIndex()])
+}
+
+// resolveReferences sets the arguments of the given value to the values of the markers
+// which they refer to. A reference such as '@validation' is resolved to the value of the
+// marker, and a reference such as '@validation.max' is resolved to its argument.
+func (definition *Definition) resolveReferences(value interface{}, references []markerReference, markerValues MarkerValues) (interface{}, error) {
+	output := reflect.New(definition.Output.Type).Elem()
+	output.Set(reflect.ValueOf(value))
+
+	for _, reference := range references {
+		referencedValue, err := resolveReference(reference.Reference, markerValues)
+
+		if err != nil {
+			return nil, fmt.Errorf("argument %q: %w", reference.ArgumentName, err)
+		}
+
+		fieldValue := output.FieldByName(definition.Output.FieldNames[reference.ArgumentName])
+		fieldType := fieldValue.Type()
+
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+
+		if !referencedValue.Type().AssignableTo(fieldType) &&
+			(referencedValue.Kind() != fieldType.Kind() || !referencedValue.Type().ConvertibleTo(fieldType)) {
+			return nil, fmt.Errorf("argument %q: marker reference %q of type %s cannot be assigned to %s",
+				reference.ArgumentName, "@"+reference.Reference, referencedValue.Type(), fieldType)
+		}
+
+		definition.Output.Fields[reference.ArgumentName].TypeInfo.setValue(fieldValue, referencedValue)
+	}
+
+	return output.Interface(), nil
+}
+
+// resolveReference returns the value of the marker or the marker argument which the given
+// reference refers to.
+func resolveReference(reference string, markerValues MarkerValues) (reflect.Value, error) {
+	values, exists := markerValues[reference]
+	argumentName := ""
+
+	if index := strings.LastIndex(reference, "."); !exists && index != -1 {
+		values, exists = markerValues[reference[:index]]
+		argumentName = reference[index+1:]
+	}
+
+	if !exists || len(values) == 0 {
+		return reflect.Value{}, fmt.Errorf("marker reference %q cannot be resolved, there is no such marker on the node", "@"+reference)
+	}
+
+	if len(values) != 1 {
+		return reflect.Value{}, fmt.Errorf("marker reference %q is ambiguous, the marker is given %d times", "@"+reference, len(values))
+	}
+
+	referencedValue := reflect.ValueOf(values[0])
+
+	if argumentName == "" {
+		return referencedValue, nil
+	}
+
+	if referencedValue.Kind() == reflect.Struct {
+		for index := 0; index < referencedValue.NumField(); index++ {
+			argument, err := ExtractArgument(referencedValue.Type().Field(index))
+
+			if err != nil || argument.Name != argumentName {
+				continue
+			}
+
+			fieldValue := referencedValue.Field(index)
+
+			if fieldValue.Kind() == reflect.Ptr && fieldValue.IsNil() {
+				return reflect.Value{}, fmt.Errorf("marker reference %q cannot be resolved, the argument is not set", "@"+reference)
+			} else if fieldValue.Kind() == reflect.Ptr {
+				fieldValue = fieldValue.Elem()
+			}
+
+			return fieldValue, nil
+		}
+	}
+
+	return reflect.Value{}, fmt.Errorf("marker reference %q cannot be resolved, the marker has no argument %q", "@"+reference, argumentName)
+}
+
+// parseUnknownArgument parses the value of the given argument which does not exist in the
+// output. The value is put into the rest field if there is any, otherwise it is skipped, or
+// it is reported as an error if the output is strict. It returns false if there is an error.
+func (definition *Definition) parseUnknownArgument(scanner *Scanner, output reflect.Value, argumentName string, negated bool) bool {
+	if definition.Output.Strict && definition.Output.RestField == "" {
+		scanner.AddError(fmt.Sprintf("unknown argument %q for marker %q", argumentName, "+"+definition.Name))
+		return false
+	}
+
+	// the negated argument such as '!argument' is false
+	var anyValue interface{} = false
+
+	if !negated {
+		(&ArgumentTypeInfo{ActualType: AnyType}).Parse(scanner, reflect.ValueOf(&anyValue))
+	}
+
+	if definition.Output.RestField == "" || anyValue == nil {
+		return true
+	}
+
+	restValue := output.FieldByName(definition.Output.RestField)
+
+	if restValue.IsNil() {
+		restValue.Set(reflect.MakeMap(restValue.Type()))
+	}
+
+	restValue.SetMapIndex(reflect.ValueOf(argumentName), reflect.ValueOf(anyValue))
+	return true
+}
+
+// TestParse parses each of the given sample markers such as '+validation:max=5' without
+// any package, and normalizes and validates the parsed values. It returns the errors in
+// the order of the samples, the error of a sample which is parsed successfully is nil.
+// It is useful for checking the struct tags and the types of a marker in its tests.
+func (definition *Definition) TestParse(samples ...string) []error {
+	errs := make([]error, len(samples))
+
+	for index, sample := range samples {
+		sample = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(sample), "//"))
+
+		if sample != "+"+definition.Name && !strings.HasPrefix(sample, "+"+definition.Name+":") &&
+			!strings.HasPrefix(sample, "+"+definition.Name+"=") && !strings.HasPrefix(sample, "+"+definition.Name+" ") {
+			errs[index] = fmt.Errorf("sample %q is not a marker of %s", sample, definition.Name)
+			continue
+		}
+
+		_, errs[index] = definition.parseAndValidate(sample)
+	}
+
+	return errs
+}
+
+// parseAndValidate parses the given marker, and normalizes and validates the parsed value.
+func (definition *Definition) parseAndValidate(marker string) (interface{}, error) {
+	value, seen, err := definition.ParseWithPresence(marker)
+
+	if err == nil {
+		value, err = normalizeMarker(value)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = definition.validate(value, seen)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// validate validates the arguments of the given value, and calls its Validate method
+// if it implements Marker. The seen set contains the names of the arguments set in the marker.
+func (definition *Definition) validate(value interface{}, seen map[string]bool) error {
+	err := definition.ValidateArgumentsWithPresence(value, seen)
+
+	if markerValue, ok := value.(Marker); ok && err == nil {
+		err = markerValue.Validate()
+	}
+
+	return err
+}
+
+// parsePositionalArguments parses the values separated by commas into the arguments
+// in the declaration order of their fields.
+func (definition *Definition) parsePositionalArguments(scanner *Scanner, output reflect.Value, seen map[string]bool) {
+	argumentNames := definition.positionalArgumentNames()
+
+	for index := 0; scanner.Peek() != EOF; index++ {
+		if index == len(argumentNames) {
+			scanner.AddError(fmt.Sprintf("too many positional arguments, want at most %d", len(argumentNames)))
+			return
+		}
+
+		argumentName := argumentNames[index]
+		argument := definition.Output.Fields[argumentName]
+		fieldValue := output.FieldByName(definition.Output.FieldNames[argumentName])
+
+		if !fieldValue.CanSet() {
+			return
+		}
+
+		seen[argumentName] = true
+		err := parseArgument(argument, scanner, fieldValue)
+
+		if err != nil {
+			scanner.AddError(err.Error())
+			return
+		}
+
+		allocateEmptyCollection(argument, fieldValue)
+
+		if scanner.Peek() == EOF {
+			return
+		}
+
+		if !scanner.Expect(',', "Comma ','") {
+			return
+		}
+	}
+}
+
+// parseArgument parses the value of the given argument into the field. A pointer field is
+// allocated only if the value is parsed, so that an absent optional argument stays nil
+// whereas an argument given as its zero value such as 'count=0' points to the zero value.
+func parseArgument(argument Argument, scanner *Scanner, fieldValue reflect.Value) error {
+	if fieldValue.Kind() != reflect.Ptr {
+		return argument.TypeInfo.Parse(scanner, fieldValue)
+	}
+
+	value := reflect.New(fieldValue.Type().Elem())
+	err := argument.TypeInfo.Parse(scanner, value.Elem())
+
+	if err != nil {
+		return err
+	}
+
+	fieldValue.Set(value)
+	return nil
+}
+
+// allocateEmptyCollection sets the given slice argument having allowEmpty option to an
+// empty slice if it is nil, since the empty literal such as 'tags={}' is parsed as a nil slice.
+func allocateEmptyCollection(argument Argument, fieldValue reflect.Value) {
+	if argument.AllowEmpty && fieldValue.Kind() == reflect.Slice && fieldValue.IsNil() {
+		fieldValue.Set(reflect.MakeSlice(fieldValue.Type(), 0, 0))
+	}
+}
+
+// positionalArgumentNames returns the argument names in the declaration order of their fields.
+func (definition *Definition) positionalArgumentNames() []string {
+	argumentNames := make(map[string]string, len(definition.Output.FieldNames))
+
+	for argumentName, fieldName := range definition.Output.FieldNames {
+		argumentNames[fieldName] = argumentName
+	}
+
+	names := make([]string, 0, len(argumentNames))
+
+	for index := 0; index < definition.Output.Type.NumField(); index++ {
+		if argumentName, ok := argumentNames[definition.Output.Type.Field(index).Name]; ok {
+			names = append(names, argumentName)
+		}
+	}
+
+	return names
+}
+
+// countArguments counts the arguments given with their names such as 'width=10'
+// and the arguments given without their names such as '10' in the given fields.
+func countArguments(fields string) (namedCount int, positionalCount int) {
+	scanner := NewScanner(fields)
+	scanner.SkipContinuations = true
+
+	for scanner.Peek() != EOF {
+		searchIndex := scanner.SearchIndex()
+
+		if scanner.Scan() == Identifier && scanner.SkipWhitespaces() == '=' {
+			namedCount++
+			scanner.Scan()
+		} else {
+			positionalCount++
+			scanner.SetSearchIndex(searchIndex)
+		}
+
+		var anyValue interface{}
+		(&ArgumentTypeInfo{ActualType: AnyType}).Parse(scanner, reflect.ValueOf(&anyValue))
+
+		if scanner.Scan() != ',' {
+			break
+		}
+	}
+
+	return namedCount, positionalCount
+}
+
+// ValidateArguments checks the given parsed output against the constraints
+// declared on the definition's arguments such as minItems, maxItems, min, max and requiredIf.
+// As it cannot tell an omitted optional collection from an empty one, the item counts
+// of the nil optional collections are not checked. Use ValidateArgumentsWithPresence
+// with the arguments returned by ParseWithPresence to check them as well.
+func (definition *Definition) ValidateArguments(value interface{}) error {
+	return definition.ValidateArgumentsWithPresence(value, nil)
+}
+
+// ValidateArgumentsWithPresence functions like ValidateArguments, and it checks the item
+// counts of the optional collections only if they are in the given set of the arguments
+// set in the marker. If the set is nil, the nil optional collections are skipped instead.
+func (definition *Definition) ValidateArgumentsWithPresence(value interface{}, seen map[string]bool) error {
+	if definition.Output.IsAnonymous || value == nil {
+		return nil
+	}
+
+	output := reflect.Indirect(reflect.ValueOf(value))
+
+	if output.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var errs []error
+
+	argumentNames := make([]string, 0)
+
+	for argumentName, argument := range definition.Output.Fields {
+		if argument.MinItems != 0 || argument.MaxItems != 0 {
+			argumentNames = append(argumentNames, argumentName)
+		}
+	}
+
+	sort.Strings(argumentNames)
+
+	for _, argumentName := range argumentNames {
+		argument := definition.Output.Fields[argumentName]
+		fieldValue := output.FieldByName(definition.Output.FieldNames[argumentName])
+
+		if !fieldValue.IsValid() {
+			continue
+		}
+
+		// the optional arguments which are not set are not checked
+		if seen != nil && !seen[argumentName] && !argument.Required {
+			continue
+		} else if seen == nil && fieldValue.IsNil() && !argument.Required {
+			continue
+		}
+
+		itemCount := reflect.Indirect(fieldValue).Len()
+
+		if argument.MinItems != 0 && itemCount < argument.MinItems {
+			errs = append(errs, fmt.Errorf("argument %q must have at least %d item(s), got %d", argumentName, argument.MinItems, itemCount))
+		}
+
+		if argument.MaxItems != 0 && itemCount > argument.MaxItems {
+			errs = append(errs, fmt.Errorf("argument %q must have at most %d item(s), got %d", argumentName, argument.MaxItems, itemCount))
+		}
+	}
+
+	errs = append(errs, definition.validateBounds(output)...)
+	errs = append(errs, definition.validateRequiredIf(output)...)
+
+	return NewErrorList(errs)
+}
+
+// validateBounds checks if the integer and float arguments are within their min and max bounds.
+func (definition *Definition) validateBounds(output reflect.Value) []error {
+	var errs []error
+
+	argumentNames := make([]string, 0)
+
+	for argumentName, argument := range definition.Output.Fields {
+		if argument.Min != nil || argument.Max != nil {
+			argumentNames = append(argumentNames, argumentName)
+		}
+	}
+
+	sort.Strings(argumentNames)
+
+	for _, argumentName := range argumentNames {
+		argument := definition.Output.Fields[argumentName]
+		fieldValue := reflect.Indirect(output.FieldByName(definition.Output.FieldNames[argumentName]))
+
+		// the optional arguments which are not set are not checked
+		if !fieldValue.IsValid() {
+			continue
+		}
+
+		var value float64
+
+		switch fieldValue.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			value = float64(fieldValue.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			value = float64(fieldValue.Uint())
+		case reflect.Float32, reflect.Float64:
+			value = fieldValue.Float()
+		default:
+			continue
+		}
+
+		if minBound := argument.Min; minBound != nil && minBound.Exclusive && value <= minBound.Value {
+			errs = append(errs, fmt.Errorf("argument %q must be greater than %v, got %v", argumentName, minBound.Value, fieldValue.Interface()))
+		} else if minBound != nil && !minBound.Exclusive && value < minBound.Value {
+			errs = append(errs, fmt.Errorf("argument %q must be greater than or equal to %v, got %v", argumentName, minBound.Value, fieldValue.Interface()))
+		}
+
+		if maxBound := argument.Max; maxBound != nil && maxBound.Exclusive && value >= maxBound.Value {
+			errs = append(errs, fmt.Errorf("argument %q must be less than %v, got %v", argumentName, maxBound.Value, fieldValue.Interface()))
+		} else if maxBound != nil && !maxBound.Exclusive && value > maxBound.Value {
+			errs = append(errs, fmt.Errorf("argument %q must be less than or equal to %v, got %v", argumentName, maxBound.Value, fieldValue.Interface()))
+		}
+	}
+
+	return errs
+}
+
+// validateRequiredIf checks if the arguments are set when their requiredIf conditions are satisfied.
+func (definition *Definition) validateRequiredIf(output reflect.Value) []error {
+	var errs []error
+
+	argumentNames := make([]string, 0)
+
+	for argumentName, argument := range definition.Output.Fields {
+		if argument.RequiredIf != nil {
+			argumentNames = append(argumentNames, argumentName)
+		}
+	}
+
+	sort.Strings(argumentNames)
+
+	for _, argumentName := range argumentNames {
+		condition := definition.Output.Fields[argumentName].RequiredIf
+		conditionValue := output.FieldByName(definition.Output.FieldNames[condition.Argument])
+
+		// the condition is not satisfied if the compared optional argument is not set
+		if !conditionValue.IsValid() || conditionValue.Kind() == reflect.Ptr && conditionValue.IsNil() {
+			continue
+		}
+
+		if fmt.Sprint(reflect.Indirect(conditionValue).Interface()) != condition.Value {
+			continue
+		}
+
+		fieldValue := output.FieldByName(definition.Output.FieldNames[argumentName])
+
+		if fieldValue.IsValid() && isZeroOrNil(fieldValue) {
+			errs = append(errs, fmt.Errorf("argument %q is required when argument %q is %q", argumentName, condition.Argument, condition.Value))
+		}
+	}
+
+	return errs
+}
+
+// isZeroOrNil checks if the given value is a nil pointer, or the zero value of its type
+// such as an empty string or a nil slice.
+func isZeroOrNil(value reflect.Value) bool {
+	return reflect.DeepEqual(value.Interface(), reflect.Zero(value.Type()).Interface())
+}
+
+// ValidateNode validates the values of the definition against all marker values
+// associated with the same node. It is invoked once per node after all its markers are parsed.
+func (definition *Definition) ValidateNode(markerValues MarkerValues) error {
+	var errs []error
+
+	for _, value := range markerValues[definition.Name] {
+		nodeMarker, ok := value.(NodeMarker)
+
+		if !ok {
+			continue
+		}
+
+		if err := nodeMarker.ValidateNode(markerValues); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return NewErrorList(errs)
+}
+
+// parseRaw captures the text after the marker name and its separator, which is a space
+// or a colon, into the 'Value' field verbatim.
+func (definition *Definition) parseRaw(marker string) interface{} {
+	output := reflect.Indirect(reflect.New(definition.Output.Type))
+	fieldValue := output.FieldByName(definition.Output.FieldNames[ValueArgument])
+
+	text := strings.TrimPrefix(marker, "+"+definition.Name)
+
+	if strings.HasPrefix(text, " ") || strings.HasPrefix(text, ":") {
+		text = text[1:]
+	}
+
+	if fieldValue.CanSet() {
+		fieldValue.Set(reflect.ValueOf(text).Convert(fieldValue.Type()))
+	}
+
+	return output.Interface()
 }
 
 func (definition *Definition) parseSyntaxFree(marker string) interface{} {
@@ -0,0 +1,1119 @@
+package marker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go/ast"
+	"math"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type testCollectionMarker struct {
+	Tags   []string       `marker:"Tags,minItems=1,maxItems=3"`
+	Labels map[string]int `marker:"Labels,optional,maxItems=1"`
+	Keys   []string       `marker:"Keys,optional,minItems=1"`
+}
+
+func TestDefinition_ValidateArgumentsItemCount(t *testing.T) {
+	testCases := []struct {
+		Marker        string
+		MustHaveError bool
+	}{
+		{"+test:Tags={a}", false},
+		{"+test:Tags={a,b,c}", false},
+		{"+test:Tags={a,b,c,d}", true},
+		{"+test:Tags={}", true},
+		{"+test:Tags={a},Labels={x:1}", false},
+		{"+test:Tags={a},Labels={x:1,y:2}", true},
+		{"+test:Tags={a},Keys={}", true},
+		{"+test:Tags={a},Keys={k}", false},
+	}
+
+	definition, err := MakeDefinition("test", "", FieldLevel, &testCollectionMarker{})
+	assert.Nil(t, err)
+
+	for _, testCase := range testCases {
+		value, seen, err := definition.ParseWithPresence(testCase.Marker)
+		assert.Nil(t, err, testCase.Marker)
+
+		err = definition.ValidateArgumentsWithPresence(value, seen)
+
+		if testCase.MustHaveError {
+			assert.NotNil(t, err, testCase.Marker)
+		} else {
+			assert.Nil(t, err, testCase.Marker)
+		}
+	}
+}
+
+func TestDefinition_ValidateArgumentsItemCountOrder(t *testing.T) {
+	definition, err := MakeDefinition("test", "", FieldLevel, &testCollectionMarker{})
+	assert.Nil(t, err)
+
+	value, seen, err := definition.ParseWithPresence("+test:Tags={a,b,c,d},Labels={x:1,y:2},Keys={}")
+	assert.Nil(t, err)
+
+	err = definition.ValidateArgumentsWithPresence(value, seen)
+	assert.NotNil(t, err)
+
+	errs := err.(ErrorList)
+	assert.Len(t, errs, 3)
+	assert.Equal(t, "argument \"Keys\" must have at least 1 item(s), got 0", errs[0].Error())
+	assert.Equal(t, "argument \"Labels\" must have at most 1 item(s), got 2", errs[1].Error())
+	assert.Equal(t, "argument \"Tags\" must have at most 3 item(s), got 4", errs[2].Error())
+}
+
+func TestMakeDefinition_ItemCountOptionOnNonCollection(t *testing.T) {
+	_, err := MakeDefinition("test", "", FieldLevel, &struct {
+		Name string `marker:"Name,minItems=1"`
+	}{})
+	assert.NotNil(t, err)
+	assert.Equal(t, "'Name' field with minItems or maxItems option can be only slice or map", err.Error())
+
+	_, err = MakeDefinition("test", "", FieldLevel, &struct {
+		Tags []string `marker:"Tags,minItems=3,maxItems=1"`
+	}{})
+	assert.NotNil(t, err)
+	assert.Equal(t, "'Tags' field cannot have minItems greater than maxItems", err.Error())
+}
+
+type testInlineMarker struct {
+	Value bool `marker:"Value,useValueSyntax"`
+}
+
+func (m testInlineMarker) ValidateNode(markerValues MarkerValues) error {
+	if markerValues.Get("json:name") == nil {
+		return errors.New("'json:inline' requires 'json:name'")
+	}
+
+	return nil
+}
+
+func TestDefinition_ValidateNode(t *testing.T) {
+	definition, err := MakeDefinition("json:inline", "", FieldLevel, &testInlineMarker{})
+	assert.Nil(t, err)
+
+	err = definition.ValidateNode(MarkerValues{
+		"json:inline": {testInlineMarker{}},
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, "['json:inline' requires 'json:name']", err.Error())
+
+	err = definition.ValidateNode(MarkerValues{
+		"json:inline": {testInlineMarker{}},
+		"json:name":   {"name"},
+	})
+	assert.Nil(t, err)
+}
+
+type testQueryMarker struct {
+	Query string `marker:"query"`
+	Url   string `marker:"url,optional"`
+}
+
+func TestDefinition_ParseQuotedValueWithEqualsSign(t *testing.T) {
+	testCases := []struct {
+		Marker   string
+		Expected testQueryMarker
+	}{
+		{`+test:query="a=b&c=d"`, testQueryMarker{Query: "a=b&c=d"}},
+		{`+test:query="a=b", url="https://example.com/?q=x&page=2"`, testQueryMarker{Query: "a=b", Url: "https://example.com/?q=x&page=2"}},
+		{`+test:url="https://example.com/?q=\"x=y\"",query=` + "`k=v`", testQueryMarker{Query: "k=v", Url: `https://example.com/?q="x=y"`}},
+	}
+
+	definition, err := MakeDefinition("test", "", FieldLevel, &testQueryMarker{})
+	assert.Nil(t, err)
+
+	for _, testCase := range testCases {
+		value, err := definition.Parse(testCase.Marker)
+		assert.Nil(t, err, testCase.Marker)
+		assert.Equal(t, testCase.Expected, value, testCase.Marker)
+	}
+
+	name, anonymousName, fields := splitMarker(`+test:"a=b"`)
+	assert.Equal(t, `test:"a=b"`, name)
+	assert.Equal(t, `test:"a=b"`, anonymousName)
+	assert.Equal(t, "", fields)
+}
+
+func TestDefinition_ParseUnterminatedString(t *testing.T) {
+	definition, err := MakeDefinition("test", "", FieldLevel, &testQueryMarker{})
+	assert.Nil(t, err)
+
+	_, err = definition.Parse(`+test:query="oops`)
+	assert.NotNil(t, err)
+	assert.Equal(t, "[unterminated string literal at column 13, '\"' is missing]", err.Error())
+
+	_, err = definition.Parse(`+test:query = "oops`)
+	assert.NotNil(t, err)
+	assert.Equal(t, "[unterminated string literal at column 15, '\"' is missing]", err.Error())
+}
+
+func TestDefinition_Levels(t *testing.T) {
+	definition, err := MakeDefinition("test", "", FieldLevel|MethodLevel, &testMarker{})
+	assert.Nil(t, err)
+	assert.Equal(t, []TargetLevel{FieldLevel, StructMethodLevel, InterfaceMethodLevel}, definition.Levels())
+
+	definition, err = MakeDefinition("test", "", 0, &testMarker{})
+	assert.Nil(t, err)
+	assert.Empty(t, definition.Levels())
+}
+
+type testFeatureMarker struct {
+	Enabled bool   `marker:"enabled,optional"`
+	Verbose *bool  `marker:"verbose,optional"`
+	Name    string `marker:"name,optional"`
+}
+
+func TestDefinition_ParseBooleanNegation(t *testing.T) {
+	falseValue := false
+
+	testCases := []struct {
+		Marker   string
+		Expected testFeatureMarker
+	}{
+		{"+feature:!verbose", testFeatureMarker{Verbose: &falseValue}},
+		{"+feature:enabled=true,!verbose", testFeatureMarker{Enabled: true, Verbose: &falseValue}},
+		{"+feature:!enabled, name=test", testFeatureMarker{Name: "test"}},
+		{"+feature:name=test, !unknown", testFeatureMarker{Name: "test"}},
+	}
+
+	definition, err := MakeDefinition("feature", "", FieldLevel, &testFeatureMarker{})
+	assert.Nil(t, err)
+
+	for _, testCase := range testCases {
+		value, err := definition.Parse(testCase.Marker)
+		assert.Nil(t, err, testCase.Marker)
+		assert.Equal(t, testCase.Expected, value, testCase.Marker)
+	}
+
+	_, err = definition.Parse("+feature:!name")
+	assert.NotNil(t, err)
+	assert.Equal(t, "['!' can only be used with boolean arguments, \"name\" is not boolean]", err.Error())
+}
+
+func TestDefinition_ParseWithCarriageReturn(t *testing.T) {
+	definition, err := MakeDefinition("test", "", FieldLevel, &testQueryMarker{})
+	assert.Nil(t, err)
+
+	markerComment := newMarkerComment(&ast.Comment{Text: "// +test:url=example.com, \\\r"})
+	markerComment.append(&ast.Comment{Text: "// query=value\r"})
+
+	value, err := definition.Parse(markerComment.Text())
+	assert.Nil(t, err)
+	assert.Equal(t, testQueryMarker{Query: "value", Url: "example.com"}, value)
+
+	value, err = definition.Parse("+test:query=value\r")
+	assert.Nil(t, err)
+	assert.Equal(t, testQueryMarker{Query: "value"}, value)
+}
+
+type testRulesMarker struct {
+	Rules []testRule `marker:"rules"`
+	Rule  *testRule  `marker:"rule,optional"`
+}
+
+func TestDefinition_ParseNestedStructs(t *testing.T) {
+	definition, err := MakeDefinition("m", "", FieldLevel, &testRulesMarker{})
+	assert.Nil(t, err)
+
+	value, err := definition.Parse("+m:rules={{name=a,level=1},{name=b,level=2}},rule={name=c}")
+	assert.Nil(t, err)
+	assert.Equal(t, testRulesMarker{
+		Rules: []testRule{{Name: "a", Level: 1}, {Name: "b", Level: 2}},
+		Rule:  &testRule{Name: "c"},
+	}, value)
+
+	_, err = definition.Parse("+m:rules={{level=1}}")
+	assert.NotNil(t, err)
+	assert.Equal(t, "[missing argument(s) name of marker.testRule]", err.Error())
+}
+
+func TestDefinition_ParseStringWhitespaces(t *testing.T) {
+	definition, err := MakeDefinition("test", "", FieldLevel, &testQueryMarker{})
+	assert.Nil(t, err)
+
+	testCases := []struct {
+		Marker   string
+		Expected testQueryMarker
+	}{
+		{`+test:query=  hello  ,url=  world  `, testQueryMarker{Query: "hello", Url: "world"}},
+		{`+test:query="  hello  ",url=` + "`  world  `", testQueryMarker{Query: "  hello  ", Url: "  world  "}},
+		{`+test:query=" ", url=""`, testQueryMarker{Query: " "}},
+	}
+
+	for _, testCase := range testCases {
+		value, err := definition.Parse(testCase.Marker)
+		assert.Nil(t, err, testCase.Marker)
+		assert.Equal(t, testCase.Expected, value, testCase.Marker)
+	}
+}
+
+func TestDefinition_ParseStringControlCharacters(t *testing.T) {
+	definition, err := MakeDefinition("test", "", FieldLevel, &testQueryMarker{})
+	assert.Nil(t, err)
+
+	_, err = definition.Parse("+test:query=hello\tworld")
+	assert.NotNil(t, err)
+	assert.Equal(t, "[unquoted value \"hello\\tworld\" cannot contain control character U+0009, use a quoted value instead]", err.Error())
+
+	_, err = definition.Parse("+test:query=hello\x00")
+	assert.NotNil(t, err)
+
+	value, err := definition.Parse("+test:query=\"hello\\tworld\"")
+	assert.Nil(t, err)
+	assert.Equal(t, testQueryMarker{Query: "hello\tworld"}, value)
+
+	value, err = definition.Parse("+test:query=\thello\t")
+	assert.Nil(t, err)
+	assert.Equal(t, testQueryMarker{Query: "hello"}, value)
+}
+
+type testUnicodeMarker struct {
+	Size int    `marker:"größe"`
+	Name string `marker:"名前,optional"`
+}
+
+func TestDefinition_ParseUnicodeNames(t *testing.T) {
+	definition, err := MakeDefinition("maß:feld", "", FieldLevel, &testUnicodeMarker{})
+	assert.Nil(t, err)
+
+	value, err := definition.Parse("+maß:feld:größe=12,名前=değer")
+	assert.Nil(t, err)
+	assert.Equal(t, testUnicodeMarker{Size: 12, Name: "değer"}, value)
+
+	registry := NewRegistry()
+	assert.Nil(t, registry.RegisterWithDefinition(definition))
+	assert.Equal(t, definition, registry.Lookup("+maß:feld:größe=12", ""))
+}
+
+func TestDefinition_ParseWithPresence(t *testing.T) {
+	definition, err := MakeDefinition("test", "", FieldLevel, &testQueryMarker{})
+	assert.Nil(t, err)
+
+	value, present, err := definition.ParseWithPresence(`+test:query=""`)
+	assert.Nil(t, err)
+	assert.Equal(t, testQueryMarker{}, value)
+	assert.Equal(t, map[string]bool{"query": true}, present)
+
+	value, present, err = definition.ParseWithPresence(`+test:query="",url=""`)
+	assert.Nil(t, err)
+	assert.Equal(t, testQueryMarker{}, value)
+	assert.True(t, present["url"])
+
+	definition, err = MakeDefinition("feature", "", FieldLevel, &testFeatureMarker{})
+	assert.Nil(t, err)
+
+	_, present, err = definition.ParseWithPresence("+feature:!enabled")
+	assert.Nil(t, err)
+	assert.True(t, present["enabled"])
+	assert.False(t, present["name"])
+}
+
+func TestDefinition_ArgumentSignature(t *testing.T) {
+	definition, err := MakeDefinition("test", "", FieldLevel, &testQueryMarker{})
+	assert.Nil(t, err)
+	assert.Equal(t, "query string, url string (optional)", definition.ArgumentSignature())
+
+	_, err = definition.Parse(`+test:query="a" url="b"`)
+	assert.NotNil(t, err)
+	assert.Equal(t, "[got \"url\"; want Comma ',' expected arguments: query string, url string (optional)]", definition.appendArgumentSignature(err).Error())
+
+	definition, err = MakeDefinition("test", "", FieldLevel, &testRulesMarker{})
+	assert.Nil(t, err)
+	assert.Equal(t, "rules []marker.testRule, rule *marker.testRule (optional)", definition.ArgumentSignature())
+
+	definition, err = MakeDefinition("test", "", FieldLevel, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "Value int", definition.ArgumentSignature())
+}
+
+type testSwitchMarker struct {
+	Enabled bool  `marker:"enabled,bool=extended"`
+	Strict  *bool `marker:"strict,optional"`
+}
+
+func TestDefinition_ParseExtendedBoolean(t *testing.T) {
+	definition, err := MakeDefinition("switch", "", FieldLevel, &testSwitchMarker{})
+	assert.Nil(t, err)
+
+	testCases := []struct {
+		Value    string
+		Expected bool
+	}{
+		{"true", true},
+		{"yes", true},
+		{"on", true},
+		{"false", false},
+		{"no", false},
+		{"off", false},
+	}
+
+	for _, testCase := range testCases {
+		value, err := definition.Parse("+switch:enabled=" + testCase.Value)
+		assert.Nil(t, err, testCase.Value)
+		assert.Equal(t, testSwitchMarker{Enabled: testCase.Expected}, value, testCase.Value)
+	}
+
+	_, err = definition.Parse("+switch:enabled=maybe")
+	assert.NotNil(t, err)
+	assert.Equal(t, "[expected true, false, yes, no, on or off, got \"maybe\"]", err.Error())
+
+	_, err = definition.Parse("+switch:enabled=yes,strict=yes")
+	assert.NotNil(t, err)
+	assert.Equal(t, "[expected true or false, got \"yes\"]", err.Error())
+
+	_, err = MakeDefinition("switch", "", FieldLevel, &struct {
+		Name string `marker:"name,bool=extended"`
+	}{})
+	assert.NotNil(t, err)
+	assert.Equal(t, "'name' field with bool=extended option can be only bool", err.Error())
+}
+
+type testRatioMarker struct {
+	Ratio float64 `marker:"ratio"`
+}
+
+func TestDefinition_ParseFloat(t *testing.T) {
+	definition, err := MakeDefinition("ratio", "", FieldLevel, &testRatioMarker{})
+	assert.Nil(t, err)
+
+	testCases := []struct {
+		Value    string
+		Expected float64
+	}{
+		{"1.5", 1.5},
+		{"-0.0", math.Copysign(0, -1)},
+		{"1e308", 1e308},
+		{"1e-320", 1e-320},
+		{"-2.5e-3", -2.5e-3},
+		{"1_000.5", 1000.5},
+		{"-1_000_000.25", -1000000.25},
+	}
+
+	for _, testCase := range testCases {
+		value, err := definition.Parse("+ratio:ratio=" + testCase.Value)
+		assert.Nil(t, err, testCase.Value)
+		assert.Equal(t, testCase.Expected, value.(testRatioMarker).Ratio, testCase.Value)
+		assert.Equal(t, math.Signbit(testCase.Expected), math.Signbit(value.(testRatioMarker).Ratio), testCase.Value)
+	}
+
+	_, err = definition.Parse("+ratio:ratio=1e400")
+	assert.NotNil(t, err)
+	assert.Equal(t, "[argument \"ratio\": unable to parse float: \"1e400\" is out of range: value out of range]", err.Error())
+
+	_, err = definition.Parse("+ratio:ratio=abc")
+	assert.NotNil(t, err)
+	assert.Equal(t, "[unable to parse float: \"abc\" is not a valid float]", err.Error())
+
+	_, err = definition.Parse("+ratio:ratio=1__000.5")
+	assert.NotNil(t, err)
+	assert.Equal(t, "[unable to parse float: \"1__000.5\" is not a valid float]", err.Error())
+
+	for _, nonFinite := range []string{"inf", "+Inf", "-Infinity", "NaN"} {
+		_, err = definition.Parse("+ratio:ratio=" + nonFinite)
+		assert.NotNil(t, err, nonFinite)
+		assert.Equal(t, "[unable to parse float: \""+nonFinite+"\" is not a finite number]", err.Error(), nonFinite)
+	}
+}
+
+func TestDefinition_ParseFloatIntoInteger(t *testing.T) {
+	definition, err := MakeDefinition("size", "", FieldLevel, &testSizeMarker{})
+	assert.Nil(t, err)
+
+	_, err = definition.Parse("+size:width=10.5, height=2")
+	assert.NotNil(t, err)
+	assert.Equal(t, "[expected integer, got float 10.5]", err.Error())
+
+	_, err = definition.Parse("+size:width=-3.25")
+	assert.NotNil(t, err)
+	assert.Equal(t, "[expected integer, got float -3.25]", err.Error())
+
+	value, err := definition.Parse("+size:width=10, height=2")
+	assert.Nil(t, err)
+	assert.Equal(t, testSizeMarker{Width: 10, Height: 2}, value)
+}
+
+type testSizeMarker struct {
+	Width  int `marker:"width"`
+	Height int `marker:"height,optional"`
+}
+
+type testColorMarker struct {
+	Red   int    `marker:"red"`
+	Green int    `marker:"green"`
+	Blue  int    `marker:"blue"`
+	Name  string `marker:"name,optional"`
+}
+
+func TestDefinition_ParsePositionalArguments(t *testing.T) {
+	definition, err := MakeDefinition("size", "", FieldLevel, &testSizeMarker{})
+	assert.Nil(t, err)
+	definition.Output.Positional = true
+
+	value, presence, err := definition.ParseWithPresence("+size:10,20")
+	assert.Nil(t, err)
+	assert.Equal(t, testSizeMarker{Width: 10, Height: 20}, value)
+	assert.Equal(t, map[string]bool{"width": true, "height": true}, presence)
+
+	value, err = definition.Parse("+size:10")
+	assert.Nil(t, err)
+	assert.Equal(t, testSizeMarker{Width: 10}, value)
+
+	value, err = definition.Parse("+size:height=20,width=10")
+	assert.Nil(t, err)
+	assert.Equal(t, testSizeMarker{Width: 10, Height: 20}, value)
+
+	_, err = definition.Parse("+size:10,height=20")
+	assert.NotNil(t, err)
+	assert.Equal(t, "[positional and named arguments cannot be mixed : +size:10,height=20]", err.Error())
+
+	_, err = definition.Parse("+size:10,20,30")
+	assert.NotNil(t, err)
+	assert.Equal(t, "[too many positional arguments, want at most 2]", err.Error())
+
+	definition, err = MakeDefinition("color", "", FieldLevel, &testColorMarker{})
+	assert.Nil(t, err)
+	definition.Output.Positional = true
+
+	value, err = definition.Parse("+color:255, 128, 0, \"orange\"")
+	assert.Nil(t, err)
+	assert.Equal(t, testColorMarker{Red: 255, Green: 128, Blue: 0, Name: "orange"}, value)
+
+	_, err = definition.Parse("+color:255,128")
+	assert.NotNil(t, err)
+	assert.Equal(t, "[missing argument \"blue\"]", err.Error())
+}
+
+type testCommandMarker struct {
+	Value string `marker:"Value,raw"`
+}
+
+func TestDefinition_ParseRaw(t *testing.T) {
+	definition, err := MakeDefinition("generate:command", "", PackageLevel, &testCommandMarker{})
+	assert.Nil(t, err)
+	assert.True(t, definition.Output.Raw)
+
+	testCases := []struct {
+		Marker   string
+		Expected string
+	}{
+		{"+generate:command go run ./gen", "go run ./gen"},
+		{"+generate:command go  run   ./gen --tags=a,b, \"c d\"", "go  run   ./gen --tags=a,b, \"c d\""},
+		{"+generate:command:x=1;y={2}", "x=1;y={2}"},
+		{"+generate:command  indented", " indented"},
+		{"+generate:command", ""},
+	}
+
+	for _, testCase := range testCases {
+		value, presence, err := definition.ParseWithPresence(testCase.Marker)
+		assert.Nil(t, err, testCase.Marker)
+		assert.Equal(t, testCommandMarker{Value: testCase.Expected}, value, testCase.Marker)
+		assert.Equal(t, map[string]bool{ValueArgument: true}, presence)
+	}
+
+	_, err = MakeDefinition("generate:command", "", PackageLevel, &struct {
+		Value []string `marker:"Value,raw"`
+	}{})
+	assert.NotNil(t, err)
+	assert.Equal(t, "'Value' field with raw option can be only string", err.Error())
+
+	_, err = MakeDefinition("generate:command", "", PackageLevel, &struct {
+		Command string `marker:"command,raw"`
+	}{})
+	assert.NotNil(t, err)
+	assert.Equal(t, "'Value' field can only have raw option", err.Error())
+}
+
+type testSeparatedMarker struct {
+	Items  []string `marker:"items,sep=|"`
+	Ports  []int    `marker:"ports,optional,sep=|"`
+	Scopes []string `marker:"scopes,optional,sep= "`
+}
+
+func TestDefinition_ParseSeparatedSlices(t *testing.T) {
+	definition, err := MakeDefinition("m", "", FieldLevel, &testSeparatedMarker{})
+	assert.Nil(t, err)
+
+	value, err := definition.Parse("+m:items=a|b|c,ports=80|443,scopes=read  write admin")
+	assert.Nil(t, err)
+	assert.Equal(t, testSeparatedMarker{
+		Items:  []string{"a", "b", "c"},
+		Ports:  []int{80, 443},
+		Scopes: []string{"read", "write", "admin"},
+	}, value)
+
+	value, err = definition.Parse("+m:items={a,b},scopes=\"read write\"")
+	assert.Nil(t, err)
+	assert.Equal(t, testSeparatedMarker{
+		Items:  []string{"a", "b"},
+		Scopes: []string{"read", "write"},
+	}, value)
+
+	_, err = definition.Parse("+m:items=a,ports=80|http")
+	assert.NotNil(t, err)
+	assert.Equal(t, "[got \"http\"; want Integer]", err.Error())
+
+	_, err = MakeDefinition("m", "", FieldLevel, &struct {
+		Name string `marker:"name,sep=|"`
+	}{})
+	assert.NotNil(t, err)
+	assert.Equal(t, "'name' field with sep option can be only slice", err.Error())
+
+	_, err = MakeDefinition("m", "", FieldLevel, &struct {
+		Items []string `marker:"items,sep=||"`
+	}{})
+	assert.NotNil(t, err)
+	assert.Equal(t, "'items' field has invalid option sep=||, the separator must be a single character", err.Error())
+}
+
+type testAuthMarker struct {
+	Auth   string  `marker:"auth"`
+	Secret string  `marker:"secret,requiredIf=auth==token"`
+	User   *string `marker:"user,requiredIf=auth==basic"`
+}
+
+func TestDefinition_ValidateArgumentsRequiredIf(t *testing.T) {
+	definition, err := MakeDefinition("auth", "", FieldLevel, &testAuthMarker{})
+	assert.Nil(t, err)
+	assert.Equal(t, &Condition{Argument: "auth", Value: "token"}, definition.Output.Fields["secret"].RequiredIf)
+	assert.False(t, definition.Output.Fields["secret"].Required)
+
+	testCases := []struct {
+		Marker string
+		Error  string
+	}{
+		{"+auth:auth=token,secret=s3cr3t", ""},
+		{"+auth:auth=basic,user=admin", ""},
+		{"+auth:auth=none", ""},
+		{"+auth:auth=token", "[argument \"secret\" is required when argument \"auth\" is \"token\"]"},
+		{"+auth:auth=basic,secret=s3cr3t", "[argument \"user\" is required when argument \"auth\" is \"basic\"]"},
+	}
+
+	for _, testCase := range testCases {
+		value, err := definition.Parse(testCase.Marker)
+		assert.Nil(t, err, testCase.Marker)
+
+		err = definition.ValidateArguments(value)
+
+		if testCase.Error == "" {
+			assert.Nil(t, err, testCase.Marker)
+			continue
+		}
+
+		assert.NotNil(t, err, testCase.Marker)
+		assert.Equal(t, testCase.Error, err.Error(), testCase.Marker)
+	}
+
+	_, err = MakeDefinition("auth", "", FieldLevel, &struct {
+		Secret string `marker:"secret,requiredIf=mode==token"`
+	}{})
+	assert.NotNil(t, err)
+	assert.Equal(t, "'secret' field is required if \"mode==token\", but there is no other argument named \"mode\"", err.Error())
+
+	_, err = MakeDefinition("auth", "", FieldLevel, &struct {
+		Secret string `marker:"secret,requiredIf=token"`
+	}{})
+	assert.NotNil(t, err)
+	assert.Equal(t, "'secret' field has invalid option requiredIf=token : condition must be in the form of argument==value, got \"token\"", err.Error())
+}
+
+type testRepeatedMarker struct {
+	Tags    []string `marker:"tag"`
+	Aliases []string `marker:"alias,optional"`
+	Name    string   `marker:"name,optional"`
+}
+
+func TestDefinition_ParseRepeatedArguments(t *testing.T) {
+	definition, err := MakeDefinition("m", "", FieldLevel, &testRepeatedMarker{})
+	assert.Nil(t, err)
+
+	value, err := definition.Parse("+m:tag=a,tag=b,tag={c,d}")
+	assert.Nil(t, err)
+	assert.Equal(t, testRepeatedMarker{Tags: []string{"a", "b", "c", "d"}}, value)
+
+	value, err = definition.Parse("+m:alias=x,tag=a,alias=y")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a"}, value.(testRepeatedMarker).Tags)
+	assert.Equal(t, []string{"x", "y"}, value.(testRepeatedMarker).Aliases)
+
+	_, err = definition.Parse("+m:tag=a,name=x,name=y")
+	assert.NotNil(t, err)
+	assert.Equal(t, "[argument \"name\" cannot be given more than once]", err.Error())
+}
+
+type testLogMarker struct {
+	Level  string `marker:"level"`
+	Format string `marker:"format,optional"`
+}
+
+func (m testLogMarker) Validate() error {
+	switch m.Level {
+	case "debug", "info", "error":
+		return nil
+	}
+
+	return fmt.Errorf("level must be one of debug, info or error, got %q", m.Level)
+}
+
+func TestDefinition_TestParse(t *testing.T) {
+	definition, err := MakeDefinition("log", "", FunctionLevel, &testLogMarker{})
+	assert.Nil(t, err)
+
+	errs := definition.TestParse(
+		"+log:level=info",
+		"// +log:level=debug,format=json",
+		"+log:format=json",
+		"+log:level=trace",
+		"+logger:level=info",
+	)
+
+	assert.Len(t, errs, 5)
+	assert.Nil(t, errs[0])
+	assert.Nil(t, errs[1])
+	assert.Equal(t, "[missing argument \"level\"]", errs[2].Error())
+	assert.Equal(t, "level must be one of debug, info or error, got \"trace\"", errs[3].Error())
+	assert.Equal(t, "sample \"+logger:level=info\" is not a marker of log", errs[4].Error())
+}
+
+func ExampleDefinition_TestParse() {
+	definition, _ := MakeDefinition("log", "", FunctionLevel, &testLogMarker{})
+
+	for _, err := range definition.TestParse("+log:level=info", "+log:format=json", "+log:level=trace") {
+		fmt.Println(err)
+	}
+
+	// Output:
+	// <nil>
+	// [missing argument "level"]
+	// level must be one of debug, info or error, got "trace"
+}
+
+type testLabelsMarker struct {
+	Tags   []string          `marker:"tags,allowEmpty"`
+	Labels map[string]string `marker:"labels,allowEmpty"`
+}
+
+func TestDefinition_ParseAllowEmpty(t *testing.T) {
+	definition, err := MakeDefinition("labels", "", StructTypeLevel, &testLabelsMarker{})
+	assert.Nil(t, err)
+	assert.True(t, definition.Output.Fields["tags"].AllowEmpty)
+	assert.True(t, definition.Output.Fields["tags"].Required)
+
+	_, err = definition.Parse("+labels:labels={}")
+	assert.NotNil(t, err)
+	assert.Equal(t, "[missing argument \"tags\"]", err.Error())
+
+	value, err := definition.Parse("+labels:tags={},labels={}")
+	assert.Nil(t, err)
+	assert.NotNil(t, value.(testLabelsMarker).Tags)
+	assert.Empty(t, value.(testLabelsMarker).Tags)
+	assert.NotNil(t, value.(testLabelsMarker).Labels)
+	assert.Empty(t, value.(testLabelsMarker).Labels)
+
+	value, err = definition.Parse("+labels:tags={a,b},labels={env:prod}")
+	assert.Nil(t, err)
+	assert.Equal(t, testLabelsMarker{Tags: []string{"a", "b"}, Labels: map[string]string{"env": "prod"}}, value)
+
+	_, err = MakeDefinition("labels", "", StructTypeLevel, &struct {
+		Name string `marker:"name,allowEmpty"`
+	}{})
+	assert.Equal(t, "'name' field with allowEmpty option can be only slice or map", err.Error())
+
+	_, err = MakeDefinition("labels", "", StructTypeLevel, &struct {
+		Tags []string `marker:"tags,optional,allowEmpty"`
+	}{})
+	assert.Equal(t, "'tags' field cannot have allowEmpty option with optional, requiredIf or minItems options", err.Error())
+}
+
+type testBoundsMarker struct {
+	Min int `marker:"min,optional"`
+	Max int `marker:"max,optional"`
+}
+
+type testOptionsMarker struct {
+	Name    string                 `marker:"name"`
+	Options map[string]interface{} `marker:"options,rest"`
+}
+
+func TestDefinition_ParseUnknownArguments(t *testing.T) {
+	definition, err := MakeDefinition("validation", "", FieldLevel, &testBoundsMarker{})
+	assert.Nil(t, err)
+
+	value, err := definition.Parse("+validation:min=1,maxx=5")
+	assert.Nil(t, err)
+	assert.Equal(t, testBoundsMarker{Min: 1}, value)
+
+	registry := NewRegistry()
+	registry.StrictArguments = true
+	assert.Nil(t, registry.RegisterWithDefinition(definition))
+	assert.False(t, definition.Output.Strict)
+	assert.True(t, registry.Lookup("+validation", "").Output.Strict)
+
+	value, err = definition.Parse("+validation:min=1,maxx=5")
+	assert.Nil(t, err)
+	assert.Equal(t, testBoundsMarker{Min: 1}, value)
+
+	_, err = registry.ParseMarker("+validation:min=1,maxx=5")
+	assert.NotNil(t, err)
+	assert.Equal(t, "[unknown argument \"maxx\" for marker \"+validation\"]", err.Error())
+
+	definition, err = MakeDefinition("options", "", FieldLevel, &testOptionsMarker{})
+	assert.Nil(t, err)
+	definition.Output.Strict = true
+	assert.Equal(t, "Options", definition.Output.RestField)
+
+	value, err = definition.Parse(`+options:name=cache,ttl=30,mode="lazy",!enabled`)
+	assert.Nil(t, err)
+	assert.Equal(t, testOptionsMarker{
+		Name: "cache",
+		Options: map[string]interface{}{
+			"ttl":     30,
+			"mode":    "lazy",
+			"enabled": false,
+		},
+	}, value)
+
+	_, err = MakeDefinition("options", "", FieldLevel, &struct {
+		Options map[string]string `marker:"options,rest"`
+	}{})
+	assert.Equal(t, "'options' field with rest option can be only map[string]interface{}", err.Error())
+}
+
+type testEndpointMarker struct {
+	Address net.IP    `marker:"address,optional"`
+	URL     *url.URL  `marker:"url,optional"`
+	Since   time.Time `marker:"since,optional"`
+}
+
+func TestDefinition_ParseBuiltinTypes(t *testing.T) {
+	definition, err := MakeDefinition("endpoint", "", FunctionLevel, &testEndpointMarker{})
+	assert.Nil(t, err)
+	assert.Equal(t, CustomType, definition.Output.Fields["address"].TypeInfo.ActualType)
+	assert.Equal(t, CustomType, definition.Output.Fields["url"].TypeInfo.ActualType)
+	assert.Equal(t, CustomType, definition.Output.Fields["since"].TypeInfo.ActualType)
+
+	value, err := definition.Parse(`+endpoint:address=192.168.1.10,url="https://example.com/books?page=2",since="2021-03-04T05:06:07Z"`)
+	assert.Nil(t, err)
+
+	endpoint := value.(testEndpointMarker)
+	assert.Equal(t, net.ParseIP("192.168.1.10"), endpoint.Address)
+	assert.Equal(t, "https://example.com/books?page=2", endpoint.URL.String())
+	assert.Equal(t, time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC), endpoint.Since)
+
+	value, err = definition.Parse(`+endpoint:address="::1"`)
+	assert.Nil(t, err)
+	assert.Equal(t, net.IPv6loopback, value.(testEndpointMarker).Address)
+
+	_, err = definition.Parse("+endpoint:address=192.168.1")
+	assert.Equal(t, "[unable to parse net.IP: \"192.168.1\" is not a valid IP address]", err.Error())
+
+	_, err = definition.Parse(`+endpoint:url="http://[::1"`)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "unable to parse url.URL")
+
+	_, err = definition.Parse(`+endpoint:since="2021-03-04"`)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "unable to parse time.Time")
+}
+
+type testRetryMarker struct {
+	Attempts int           `marker:"attempts,optional"`
+	Backoff  time.Duration `marker:"backoff,optional"`
+	Codes    []int         `marker:"codes,optional"`
+}
+
+func TestDefinition_ParseWithDefaults(t *testing.T) {
+	definition, err := MakeDefinition("retry", "", FunctionLevel, &testRetryMarker{})
+	assert.Nil(t, err)
+
+	definition.Defaults = func(out interface{}) {
+		retry := out.(*testRetryMarker)
+		retry.Attempts = 3
+		retry.Backoff = time.Second
+		retry.Codes = []int{502, 503}
+	}
+
+	value, err := definition.Parse("+retry:attempts=5")
+	assert.Nil(t, err)
+	assert.Equal(t, testRetryMarker{Attempts: 5, Backoff: time.Second, Codes: []int{502, 503}}, value)
+
+	value, err = definition.Parse("+retry:backoff=10ms,codes={429}")
+	assert.Nil(t, err)
+	assert.Equal(t, testRetryMarker{Attempts: 3, Backoff: 10 * time.Millisecond, Codes: []int{429}}, value)
+
+	value, err = definition.Parse("+retry")
+	assert.Nil(t, err)
+	assert.Equal(t, testRetryMarker{Attempts: 3, Backoff: time.Second, Codes: []int{502, 503}}, value)
+}
+
+type testDeploymentMarker struct {
+	Labels   map[string]string `marker:"labels"`
+	Replicas map[string]int    `marker:"replicas,optional"`
+	Name     string            `marker:"name,optional"`
+}
+
+func TestDefinition_ParseBracelessMap(t *testing.T) {
+	definition, err := MakeDefinition("deployment", "", StructTypeLevel, &testDeploymentMarker{})
+	assert.Nil(t, err)
+
+	testCases := []struct {
+		Marker   string
+		Expected testDeploymentMarker
+	}{
+		{
+			Marker:   "+deployment:labels={app:books,tier:backend}",
+			Expected: testDeploymentMarker{Labels: map[string]string{"app": "books", "tier": "backend"}},
+		},
+		{
+			Marker:   "+deployment:labels=app:books,tier:backend",
+			Expected: testDeploymentMarker{Labels: map[string]string{"app": "books", "tier": "backend"}},
+		},
+		{
+			Marker: "+deployment:labels=app:books, tier:backend,name=api,replicas=eu:2,us:3",
+			Expected: testDeploymentMarker{
+				Labels:   map[string]string{"app": "books", "tier": "backend"},
+				Replicas: map[string]int{"eu": 2, "us": 3},
+				Name:     "api",
+			},
+		},
+		{
+			Marker:   `+deployment:name=api,labels="app.kubernetes.io/name":"books api",tier:backend`,
+			Expected: testDeploymentMarker{Labels: map[string]string{"app.kubernetes.io/name": "books api", "tier": "backend"}, Name: "api"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		value, err := definition.Parse(testCase.Marker)
+		assert.Nil(t, err, testCase.Marker)
+		assert.Equal(t, testCase.Expected, value, testCase.Marker)
+	}
+
+	_, err = definition.Parse("+deployment:labels=app")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "want Colon ':'")
+}
+
+type testSettingMarker struct {
+	Value  interface{} `marker:"value,optional"`
+	Values interface{} `marker:"values,optional"`
+}
+
+func TestDefinition_ParseInferredNumbers(t *testing.T) {
+	definition, err := MakeDefinition("setting", "", FieldLevel, &testSettingMarker{})
+	assert.Nil(t, err)
+
+	value, err := definition.Parse("+setting:value=10,values={1, 2.5, -3, 1e3}")
+	assert.Nil(t, err)
+	assert.Equal(t, testSettingMarker{
+		Value:  10,
+		Values: []interface{}{1, 2.5, -3, 1000.0},
+	}, value)
+
+	value, err = definition.Parse("+setting:value=-2.5,values={1, 2, 3}")
+	assert.Nil(t, err)
+	assert.Equal(t, testSettingMarker{Value: -2.5, Values: []int{1, 2, 3}}, value)
+
+	value, err = definition.Parse("+setting:value=1.2.3,values={a, 1}")
+	assert.Nil(t, err)
+	assert.Equal(t, testSettingMarker{Value: "1.2.3", Values: []string{"a", "1"}}, value)
+
+	registry := NewRegistry()
+	registry.PreferFloat = true
+	assert.Nil(t, registry.RegisterWithDefinition(definition))
+	assert.False(t, definition.Output.PreferFloat)
+
+	value, err = registry.ParseMarker("+setting:value=10,values={1, 2.5, -3, 1e3}")
+	assert.Nil(t, err)
+	assert.Equal(t, testSettingMarker{
+		Value:  10.0,
+		Values: []float64{1, 2.5, -3, 1000},
+	}, value)
+}
+
+type testThermostatMarker struct {
+	Temperature float64 `marker:"temperature,min=-273.15,maxExclusive=100"`
+	Level       *int    `marker:"level,optional,minExclusive=-10,max=10"`
+}
+
+func TestDefinition_ValidateArgumentsBounds(t *testing.T) {
+	definition, err := MakeDefinition("thermostat", "", FieldLevel, &testThermostatMarker{})
+	assert.Nil(t, err)
+	assert.Equal(t, &Bound{Value: -273.15}, definition.Output.Fields["temperature"].Min)
+	assert.Equal(t, &Bound{Value: 100, Exclusive: true}, definition.Output.Fields["temperature"].Max)
+
+	testCases := []struct {
+		Marker string
+		Error  string
+	}{
+		{Marker: "+thermostat:temperature=-273.15"},
+		{Marker: "+thermostat:temperature=99.99,level=10"},
+		{Marker: "+thermostat:temperature=0,level=-9"},
+		{
+			Marker: "+thermostat:temperature=-273.16",
+			Error:  "[argument \"temperature\" must be greater than or equal to -273.15, got -273.16]",
+		},
+		{
+			Marker: "+thermostat:temperature=100",
+			Error:  "[argument \"temperature\" must be less than 100, got 100]",
+		},
+		{
+			Marker: "+thermostat:temperature=20,level=-10",
+			Error:  "[argument \"level\" must be greater than -10, got -10]",
+		},
+		{
+			Marker: "+thermostat:temperature=20,level=11",
+			Error:  "[argument \"level\" must be less than or equal to 10, got 11]",
+		},
+	}
+
+	for _, testCase := range testCases {
+		value, err := definition.Parse(testCase.Marker)
+		assert.Nil(t, err, testCase.Marker)
+
+		err = definition.ValidateArguments(value)
+
+		if testCase.Error == "" {
+			assert.Nil(t, err, testCase.Marker)
+		} else {
+			assert.NotNil(t, err, testCase.Marker)
+			assert.Equal(t, testCase.Error, err.Error(), testCase.Marker)
+		}
+	}
+
+	_, err = MakeDefinition("thermostat", "", FieldLevel, &struct {
+		Name string `marker:"name,min=1"`
+	}{})
+	assert.Equal(t, "'name' field with min or max option can be only integer or float", err.Error())
+
+	_, err = MakeDefinition("thermostat", "", FieldLevel, &struct {
+		Level int `marker:"level,min=1,minExclusive=2"`
+	}{})
+	assert.Equal(t, "'level' field cannot have both min and minExclusive options", err.Error())
+
+	_, err = MakeDefinition("thermostat", "", FieldLevel, &struct {
+		Level int `marker:"level,min=low"`
+	}{})
+	assert.Equal(t, "'level' field has invalid option min=low : bound must be a number, got \"low\"", err.Error())
+}
+
+type testConstantMarker struct {
+	Data []byte `marker:"data"`
+}
+
+func TestDefinition_ParseRawBytes(t *testing.T) {
+	definition, err := MakeDefinition("constant", "", FieldLevel, &testConstantMarker{})
+	assert.Nil(t, err)
+
+	testCases := []struct {
+		Marker   string
+		Expected []byte
+	}{
+		{`+constant:data="hex:deadbeef"`, []byte{0xde, 0xad, 0xbe, 0xef}},
+		{`+constant:data="base64:aGVsbG8="`, []byte("hello")},
+		{`+constant:data="hello"`, []byte("hello")},
+	}
+
+	for _, testCase := range testCases {
+		value, err := definition.Parse(testCase.Marker)
+		assert.Nil(t, err, testCase.Marker)
+		assert.Equal(t, testCase.Expected, value.(testConstantMarker).Data, testCase.Marker)
+	}
+
+	_, err = definition.Parse(`+constant:data="hex:deadbeefx"`)
+	assert.NotNil(t, err)
+	assert.Equal(t, "[unable to decode hex: encoding/hex: invalid byte: U+0078 'x']", err.Error())
+
+	_, err = definition.Parse(`+constant:data="base64:a"`)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "unable to decode base64")
+}
+
+type testPageSizeMarker struct {
+	Size    *int      `marker:"size,optional"`
+	Label   *string   `marker:"label,optional"`
+	Visible *bool     `marker:"visible,optional"`
+	Tags    *[]string `marker:"tags,optional"`
+}
+
+func TestDefinition_ParseOptionalPointers(t *testing.T) {
+	definition, err := MakeDefinition("page", "", FieldLevel, &testPageSizeMarker{})
+	assert.Nil(t, err)
+
+	value, err := definition.Parse("+page")
+	assert.Nil(t, err)
+	assert.Equal(t, testPageSizeMarker{}, value)
+
+	value, err = definition.Parse(`+page:size=0, label="", visible=false, tags={}`)
+	assert.Nil(t, err)
+
+	marker := value.(testPageSizeMarker)
+	assert.NotNil(t, marker.Size)
+	assert.Equal(t, 0, *marker.Size)
+	assert.NotNil(t, marker.Label)
+	assert.Equal(t, "", *marker.Label)
+	assert.NotNil(t, marker.Visible)
+	assert.False(t, *marker.Visible)
+	assert.NotNil(t, marker.Tags)
+
+	value, err = definition.Parse("+page:tags=a, tags=b")
+	assert.Nil(t, err)
+	assert.Nil(t, value.(testPageSizeMarker).Size)
+	assert.Equal(t, []string{"a", "b"}, *value.(testPageSizeMarker).Tags)
+}
+
+type testWebhookMarker struct {
+	Url     string          `marker:"url"`
+	Payload json.RawMessage `marker:"payload"`
+}
+
+type testScheduleReferenceMarker struct {
+	Name     string `marker:"name"`
+	Schedule string `marker:"schedule,optional,reference"`
+}
+
+func TestDefinition_ParseAtSignValues(t *testing.T) {
+	definition, err := MakeDefinition("test", "", TypeLevel, &testScheduleReferenceMarker{})
+	assert.Nil(t, err)
+
+	value, err := definition.Parse("+test:name=@admin")
+	assert.Nil(t, err)
+	assert.Equal(t, testScheduleReferenceMarker{Name: "@admin"}, value)
+
+	_, err = definition.Parse("+test:name=@admin, schedule=@daily")
+	assert.NotNil(t, err)
+	assert.Equal(t, "marker reference \"@daily\" can only be resolved by the collector", err.(ErrorList)[0].(ScannerError).Message)
+}
+
+func TestDefinition_ParseRawJSON(t *testing.T) {
+	definition, err := MakeDefinition("webhook", "", FunctionLevel, &testWebhookMarker{})
+	assert.Nil(t, err)
+
+	payload := `{"event": "created", "tags": ["a", "b"], "meta": {"note": "braces } in ] strings", "escaped": "\"{"}}`
+
+	value, err := definition.Parse(`+webhook:payload=` + payload + `, url="https://example.com"`)
+	assert.Nil(t, err)
+	assert.Equal(t, testWebhookMarker{Url: "https://example.com", Payload: json.RawMessage(payload)}, value)
+	assert.True(t, json.Valid(value.(testWebhookMarker).Payload))
+
+	value, err = definition.Parse(`+webhook:url="https://example.com", payload=[1, [2, 3]]`)
+	assert.Nil(t, err)
+	assert.Equal(t, json.RawMessage(`[1, [2, 3]]`), value.(testWebhookMarker).Payload)
+
+	_, err = definition.Parse(`+webhook:url="https://example.com", payload={"tags": ["a"}`)
+	assert.NotNil(t, err)
+	assert.Equal(t, "[unbalanced raw JSON value, got '}' but ']' is expected]", err.Error())
+
+	_, err = definition.Parse(`+webhook:url="https://example.com", payload={"tags": ["a"]`)
+	assert.NotNil(t, err)
+	assert.Equal(t, "[unterminated raw JSON value, '}' is missing]", err.Error())
+
+	_, err = definition.Parse(`+webhook:url="https://example.com", payload=test`)
+	assert.NotNil(t, err)
+	assert.Equal(t, "[expected '{' or '[' for raw JSON value, got 't']", err.Error())
+}
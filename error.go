@@ -39,6 +39,9 @@ func (err ImportError) Error() string {
 type ParserError struct {
 	FileName string
 	Position Position
+	// End is the position after the marker comment or the node which the error belongs to,
+	// so that the range from Position to End can be highlighted.
+	End Position
 	error
 }
 
@@ -56,25 +59,23 @@ func (errorList ErrorList) Error() string {
 	return fmt.Sprintf("%v", []error(errorList))
 }
 
-func toParseError(err error, node ast.Node, position token.Position) error {
+func toParseError(err error, node ast.Node, position token.Position, end token.Position) error {
 
 	errorList, ok := err.(ErrorList)
 
 	if !ok {
 		return ParserError{
 			FileName: position.Filename,
-			Position: Position{
-				Line:   position.Line,
-				Column: position.Column,
-			},
-			error: err,
+			Position: toPosition(position),
+			End:      toPosition(end),
+			error:    err,
 		}
 	}
 
 	errors := make(ErrorList, len(errorList))
 
 	for index, errorElement := range errorList {
-		errors[index] = toParseError(errorElement, node, position)
+		errors[index] = toParseError(errorElement, node, position, end)
 	}
 
 	return errors
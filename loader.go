@@ -42,7 +42,8 @@ func newLoader(config *packages.Config, patterns ...string) *loader {
 
 // load loads and returns the Go packages.
 //
-// load returns an error if any of the patterns was invalid.
+// load returns an error if any of the patterns was invalid. If any of the
+// loaded packages has errors, they are returned as an ErrorList along with the packages.
 func (loader *loader) load() ([]*Package, error) {
 	pkgs, err := packages.Load(loader.config, loader.patterns...)
 
@@ -50,8 +51,14 @@ func (loader *loader) load() ([]*Package, error) {
 		return nil, err
 	}
 
+	var errs []error
+
 	for _, pkg := range pkgs {
 
+		for _, pkgErr := range pkg.Errors {
+			errs = append(errs, pkgErr)
+		}
+
 		if loader.packageMap[pkg] == nil {
 			loader.packageMap[pkg] = newPackage(pkg, loader)
 			pkg.Fset = loader.config.Fset
@@ -61,10 +68,11 @@ func (loader *loader) load() ([]*Package, error) {
 
 	}
 
-	return loader.packages, nil
+	return loader.packages, NewErrorList(errs)
 }
 
-// LoadPackages loads and returns the Go packages by the given patterns.
+// LoadPackages loads and returns the Go packages by the given patterns
+// with the syntax, file set and type information needed by the collector.
 func LoadPackages(patterns ...string) ([]*Package, error) {
 	return LoadPackagesWithConfig(&packages.Config{}, patterns...)
 }
@@ -62,3 +62,11 @@ func TestLoadMultiPackages(t *testing.T) {
 
 	assert.NotNil(t, pkgs[1].Module)
 }
+
+func TestLoadPackagesWithErrors(t *testing.T) {
+	pkgs, err := LoadPackages("./test/nonexistent")
+
+	assert.NotNil(t, err)
+	assert.Len(t, pkgs, 1)
+	assert.NotEmpty(t, pkgs[0].Errors)
+}
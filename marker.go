@@ -4,7 +4,11 @@ import (
 	"errors"
 	"go/ast"
 	"go/token"
+	"reflect"
+	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 // TargetLevel describes which kind of nodes a given marker are associated with.
@@ -27,6 +31,12 @@ const (
 	StructMethodLevel
 	// InterfaceMethodLevel indicates that a marker is associated with an interface method.
 	InterfaceMethodLevel
+	// FunctionLiteralLevel indicates that a marker is associated with a function literal
+	// such as a closure assigned to a variable or passed as an argument.
+	FunctionLiteralLevel
+	// ValueLevel indicates that a marker is associated with a constant or a variable
+	// specification such as a member of a const block.
+	ValueLevel
 )
 
 // Combined levels
@@ -37,15 +47,42 @@ const (
 	MethodLevel = StructMethodLevel | InterfaceMethodLevel
 )
 
+// allLevels is the combination of all the individual levels.
+const allLevels = ValueLevel<<1 - 1
+
 type Marker interface {
 	Validate() error
 }
 
+// NormalizableMarker is implemented by markers which need to be transformed after
+// they are parsed such as applying defaults depending on other fields. Normalize is
+// called right after the marker is parsed and before Validate is called, so that
+// the normalized value is validated.
+type NormalizableMarker interface {
+	Normalize() error
+}
+
+// NodeMarker is implemented by markers which need to be validated against
+// the other markers associated with the same node.
+type NodeMarker interface {
+	ValidateNode(markerValues MarkerValues) error
+}
+
+// CascadingMarker is implemented by type level markers which apply to all fields of the
+// struct type as well, such as '+json:snakecase' making all fields use snake-case names.
+// The markers are cascaded if Cascade returns true, see ComputeFieldMarkers.
+type CascadingMarker interface {
+	Cascade() bool
+}
+
 // Reserved markers
 const (
 	ImportMarkerName = "import"
 )
 
+// StructTagMarkerKey is the struct tag key whose value is collected as a marker.
+const StructTagMarkerKey = "marker"
+
 type ImportMarker struct {
 	Value string `marker:"Value,useValueSyntax"`
 	Alias string `marker:"Alias,optional"`
@@ -82,14 +119,27 @@ func (m ImportMarker) GetPkgVersion() string {
 	return ""
 }
 
-func (m ImportMarker) GetCommand() string {
+// GetCommand returns the processor commands given after the colon in Pkg.
+// A module can expose more than one command separated by commas such as
+// "github.com/procyon-projects/chrono@v1.0.0:chrono-gen,chrono-mock".
+func (m ImportMarker) GetCommand() []string {
 	pkgParts := strings.Split(m.Pkg, ":")
 
-	if len(pkgParts) > 1 {
-		return pkgParts[1]
+	if len(pkgParts) < 2 {
+		return nil
 	}
 
-	return ""
+	commands := make([]string, 0)
+
+	for _, command := range strings.Split(pkgParts[1], ",") {
+		command = strings.TrimSpace(command)
+
+		if command != "" {
+			commands = append(commands, command)
+		}
+	}
+
+	return commands
 }
 
 type MarkerValues map[string][]interface{}
@@ -104,6 +154,171 @@ func (markerValues MarkerValues) Get(name string) interface{} {
 	return result[0]
 }
 
+// MergeStrategy describes how the values of a marker existing in both
+// of the merged MarkerValues are combined.
+type MergeStrategy int
+
+const (
+	// OverrideStrategy replaces the values of a marker with the values in the other MarkerValues.
+	OverrideStrategy MergeStrategy = iota
+	// AppendStrategy appends the values in the other MarkerValues to the values of a marker.
+	AppendStrategy
+)
+
+// Merge returns new MarkerValues combining the marker values with the other
+// marker values by using the given strategy. The markers existing in only one of
+// them are kept as they are. For example, the markers of a base type can be merged
+// with the markers of a derived type so that the derived type overrides the base type.
+func (markerValues MarkerValues) Merge(other MarkerValues, strategy MergeStrategy) MarkerValues {
+	result := make(MarkerValues, len(markerValues)+len(other))
+
+	for name, values := range markerValues {
+		result[name] = append([]interface{}{}, values...)
+	}
+
+	for name, values := range other {
+		if _, exists := result[name]; exists && strategy == AppendStrategy {
+			result[name] = append(result[name], values...)
+		} else {
+			result[name] = append([]interface{}{}, values...)
+		}
+	}
+
+	return result
+}
+
+// ComputeFieldMarkers returns the effective markers of a field by merging the cascading
+// markers of its struct type with the markers of the field. The markers of the field
+// override the cascading markers with the same name, and the type markers which do not
+// implement CascadingMarker are not included.
+func ComputeFieldMarkers(typeMarkers MarkerValues, fieldMarkers MarkerValues) MarkerValues {
+	cascadingMarkers := make(MarkerValues)
+
+	for name, values := range typeMarkers {
+		for _, value := range values {
+			if cascadingMarker, ok := value.(CascadingMarker); ok && cascadingMarker.Cascade() {
+				cascadingMarkers[name] = append(cascadingMarkers[name], value)
+			}
+		}
+	}
+
+	return cascadingMarkers.Merge(fieldMarkers, OverrideStrategy)
+}
+
+// Clone returns a deep copy of the marker values. The slices, the maps and the pointers
+// in the values are copied as well, so that the copy can be modified without affecting
+// the original marker values.
+func (markerValues MarkerValues) Clone() MarkerValues {
+	if markerValues == nil {
+		return nil
+	}
+
+	result := make(MarkerValues, len(markerValues))
+
+	for name, values := range markerValues {
+		clonedValues := make([]interface{}, len(values))
+
+		for index, value := range values {
+			if value != nil {
+				clonedValues[index] = deepCopy(reflect.ValueOf(value)).Interface()
+			}
+		}
+
+		result[name] = clonedValues
+	}
+
+	return result
+}
+
+// deepCopy returns a copy of the given value which does not share any slice, map or pointer
+// with it. The unexported fields of the structs are copied as they are.
+func deepCopy(value reflect.Value) reflect.Value {
+	switch value.Kind() {
+	case reflect.Ptr:
+		if value.IsNil() {
+			return value
+		}
+
+		copied := reflect.New(value.Type().Elem())
+		copied.Elem().Set(deepCopy(value.Elem()))
+		return copied
+	case reflect.Interface:
+		if value.IsNil() {
+			return value
+		}
+
+		copied := reflect.New(value.Type()).Elem()
+		copied.Set(deepCopy(value.Elem()))
+		return copied
+	case reflect.Slice:
+		if value.IsNil() {
+			return value
+		}
+
+		copied := reflect.MakeSlice(value.Type(), value.Len(), value.Len())
+
+		for index := 0; index < value.Len(); index++ {
+			copied.Index(index).Set(deepCopy(value.Index(index)))
+		}
+
+		return copied
+	case reflect.Array:
+		copied := reflect.New(value.Type()).Elem()
+
+		for index := 0; index < value.Len(); index++ {
+			copied.Index(index).Set(deepCopy(value.Index(index)))
+		}
+
+		return copied
+	case reflect.Map:
+		if value.IsNil() {
+			return value
+		}
+
+		copied := reflect.MakeMapWithSize(value.Type(), value.Len())
+
+		for _, key := range value.MapKeys() {
+			copied.SetMapIndex(key, deepCopy(value.MapIndex(key)))
+		}
+
+		return copied
+	case reflect.Struct:
+		copied := reflect.New(value.Type()).Elem()
+		copied.Set(value)
+
+		for index := 0; index < value.NumField(); index++ {
+			if copied.Field(index).CanSet() {
+				copied.Field(index).Set(deepCopy(value.Field(index)))
+			}
+		}
+
+		return copied
+	}
+
+	return value
+}
+
+// normalizeMarker calls the Normalize method of the given value if it implements
+// NormalizableMarker, and returns the normalized value. The method can have
+// a pointer receiver to modify the value.
+func normalizeMarker(value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	pointer := reflect.New(reflect.TypeOf(value))
+	pointer.Elem().Set(reflect.ValueOf(value))
+
+	normalizableMarker, ok := pointer.Interface().(NormalizableMarker)
+
+	if !ok {
+		return value, nil
+	}
+
+	err := normalizableMarker.Normalize()
+	return pointer.Elem().Interface(), err
+}
+
 type markerComment struct {
 	commentLines []*ast.Comment
 }
@@ -118,27 +333,70 @@ func newMarkerComment(comment *ast.Comment) *markerComment {
 	return markerComment
 }
 
+// newStructTagMarkerComment returns a marker comment for the marker in the given
+// struct tag, or nil if the tag does not contain any marker.
+func newStructTagMarkerComment(tag *ast.BasicLit) *markerComment {
+	if tag == nil {
+		return nil
+	}
+
+	tagValue, err := strconv.Unquote(tag.Value)
+
+	if err != nil {
+		return nil
+	}
+
+	marker, ok := reflect.StructTag(tagValue).Lookup(StructTagMarkerKey)
+	marker = strings.TrimSpace(marker)
+
+	if !ok || len(marker) < 1 || marker[0] != '+' {
+		return nil
+	}
+
+	return newMarkerComment(&ast.Comment{
+		Slash: tag.Pos(),
+		Text:  "//" + marker,
+	})
+}
+
+// Pos returns the position of the first comment line of the marker.
 func (c markerComment) Pos() token.Pos {
 	return c.commentLines[0].Pos()
 }
 
+// End returns the position after the last comment line of the marker, so that the range
+// of a marker continued in the following lines covers all the lines.
 func (c markerComment) End() token.Pos {
-	return c.commentLines[len(c.commentLines)].End()
+	return c.commentLines[len(c.commentLines)-1].End()
 }
 
 func (c *markerComment) append(comment *ast.Comment) {
 	c.commentLines = append(c.commentLines, comment)
 }
 
+// RawText returns the original text of the comment lines which the marker consists of.
+func (c *markerComment) RawText() string {
+	lines := make([]string, 0, len(c.commentLines))
+
+	for _, line := range c.commentLines {
+		lines = append(lines, line.Text)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Text returns the marker text by joining the normalized comment lines, see NormalizeComment.
 func (c *markerComment) Text() string {
 	var text string
 	for _, line := range c.commentLines {
-		comment := strings.TrimSpace(line.Text[2:])
-
-		if strings.HasSuffix(comment, "\\") {
-			comment = strings.TrimSpace(comment[:len(comment)-1])
+		// the lines of a multiline raw string are kept as they are
+		if text != "" && isRawStringOpen(text) {
+			text = text + "\n" + strings.TrimPrefix(line.Text[2:], " ")
+			continue
 		}
 
+		comment := NormalizeComment(line.Text)
+
 		if text == "" {
 			text = comment
 		} else {
@@ -151,13 +409,14 @@ func (c *markerComment) Text() string {
 func splitMarker(marker string) (name string, anonymousName string, options string) {
 	marker = marker[1:]
 
-	nameFieldParts := strings.SplitN(marker, "=", 2)
+	// an equals sign inside a quoted value must not be treated as a separator
+	separatorIndex := indexOutsideQuotes(marker, '=')
 
-	if len(nameFieldParts) == 1 {
-		return nameFieldParts[0], nameFieldParts[0], ""
+	if separatorIndex == -1 {
+		return marker, marker, ""
 	}
 
-	anonymousName = nameFieldParts[0]
+	anonymousName = marker[:separatorIndex]
 	name = anonymousName
 
 	nameParts := strings.Split(name, ":")
@@ -166,24 +425,172 @@ func splitMarker(marker string) (name string, anonymousName string, options stri
 		name = strings.Join(nameParts[:len(nameParts)-1], ":")
 	}
 
-	return name, anonymousName, nameFieldParts[1]
+	return name, anonymousName, marker[separatorIndex+1:]
 }
 
+// convertParenthesizedMarker converts the marker in the form of '+name(x=1, y=2)'
+// into the form of '+name:x=1, y=2'. The opening parenthesis must directly follow
+// the marker name and the marker must end with the closing parenthesis, otherwise
+// the marker is returned as it is.
+func convertParenthesizedMarker(marker string) string {
+	marker = strings.TrimSpace(marker)
+
+	if len(marker) < 2 || marker[0] != '+' || !strings.HasSuffix(marker, ")") {
+		return marker
+	}
+
+	openIndex := strings.IndexFunc(marker[1:], func(character rune) bool {
+		return !IsIdentifier(character, 1) && character != ':' && character != '-' && character != '.'
+	}) + 1
+
+	if openIndex < 2 || marker[openIndex] != '(' {
+		return marker
+	}
+
+	arguments := strings.TrimSpace(marker[openIndex+1 : len(marker)-1])
+
+	if arguments == "" {
+		return marker[:openIndex]
+	}
+
+	return marker[:openIndex] + ":" + arguments
+}
+
+// getMarkerNameCandidates returns the possible marker names for the given
+// colon-delimited name from the longest to the shortest. For example,
+// 'a:b:c' results in 'a:b:c', 'a:b' and 'a'.
+func getMarkerNameCandidates(name string) []string {
+	candidates := make([]string, 0)
+
+	for {
+		candidates = append(candidates, name)
+		separatorIndex := strings.LastIndex(name, ":")
+
+		if separatorIndex == -1 {
+			return candidates
+		}
+
+		name = name[:separatorIndex]
+	}
+}
+
+// indexOutsideQuotes returns the index of the first instance of character
+// which is not enclosed in quotes, or -1 if there is no such instance.
+func indexOutsideQuotes(str string, character byte) int {
+	var quote byte
+
+	for index := 0; index < len(str); index++ {
+		switch current := str[index]; {
+		case quote != 0 && current == '\\' && quote != '`':
+			index++
+		case quote != 0 && current == quote:
+			quote = 0
+		case quote == 0 && (current == '"' || current == '`'):
+			quote = current
+		case quote == 0 && current == character:
+			return index
+		}
+	}
+
+	return -1
+}
+
+// NormalizeComment returns the text of the given line comment '// text' or
+// block comment '/* text */' without the comment characters, the surrounding
+// whitespaces and the trailing continuation character '\\'.
+func NormalizeComment(comment string) string {
+	text := getCommentContent(comment)
+
+	if strings.HasSuffix(text, "\\") {
+		text = strings.TrimSpace(text[:len(text)-1])
+	}
+
+	return text
+}
+
+// getCommentContent returns the content of the given comment without
+// the comment characters and the surrounding whitespaces. The carriage returns
+// of CRLF line endings are removed as well.
+func getCommentContent(comment string) string {
+	comment = strings.ReplaceAll(comment, "\r", "")
+
+	if strings.HasPrefix(comment, "//") {
+		return strings.TrimSpace(comment[2:])
+	}
+
+	if strings.HasPrefix(comment, "/*") {
+		return strings.TrimSpace(strings.TrimSuffix(comment[2:], "*/"))
+	}
+
+	return strings.TrimSpace(comment)
+}
+
+// splitBlockComment splits the given multiline block comment into line comments
+// so that each line can be checked for a marker. The leading '*' characters of
+// the lines are ignored. Any other comment is returned as it is.
+func splitBlockComment(comment *ast.Comment) []*ast.Comment {
+	if !strings.HasPrefix(comment.Text, "/*") || !strings.Contains(comment.Text, "\n") {
+		return []*ast.Comment{comment}
+	}
+
+	lineComments := make([]*ast.Comment, 0)
+	content := strings.TrimSuffix(comment.Text[2:], "*/")
+	offset := 2
+
+	for _, line := range strings.Split(content, "\n") {
+		text := strings.TrimSpace(line)
+		text = strings.TrimPrefix(text, "*")
+
+		if strings.TrimSpace(text) != "" {
+			lineComments = append(lineComments, &ast.Comment{
+				Slash: comment.Slash + token.Pos(offset),
+				Text:  "//" + text,
+			})
+		}
+
+		offset += len(line) + 1
+	}
+
+	return lineComments
+}
+
+// isMarkerComment checks if the content of the given comment begins with a marker such as
+// '// +marker'. The prose mentioning a marker such as '// see +import' or the text such as
+// '// +1 for this' is not a marker, since the marker name must begin with a letter.
 func isMarkerComment(comment string) bool {
-	if comment[0:2] != "//" {
+	if !strings.HasPrefix(comment, "//") && !strings.HasPrefix(comment, "/*") {
 		return false
 	}
 
-	stripped := strings.TrimSpace(comment[2:])
+	stripped := getCommentContent(comment)
 
-	if len(stripped) < 1 || stripped[0] != '+' {
+	if len(stripped) < 2 || stripped[0] != '+' {
 		return false
 	}
 
-	return true
+	character, _ := utf8.DecodeRuneInString(stripped[1:])
+	return unicode.IsLetter(character)
+}
+
+// isRawStringOpen reports whether the given text contains a raw string
+// literal which has not been closed yet.
+func isRawStringOpen(text string) bool {
+	var quote byte
+
+	for index := 0; index < len(text); index++ {
+		switch current := text[index]; {
+		case quote == '"' && current == '\\':
+			index++
+		case quote != 0 && current == quote:
+			quote = 0
+		case quote == 0 && (current == '"' || current == '`'):
+			quote = current
+		}
+	}
+
+	return quote == '`'
 }
 
 func hasContinuationCharacter(comment string) bool {
-	stripped := strings.TrimSpace(comment[2:])
-	return strings.HasSuffix(stripped, "\\")
+	return strings.HasSuffix(getCommentContent(comment), "\\")
 }
@@ -0,0 +1,278 @@
+package marker
+
+import (
+	"github.com/stretchr/testify/assert"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+type testTemplateMarker struct {
+	Template string `marker:"template"`
+	Name     string `marker:"Name"`
+}
+
+func parseTestMarkerComments(t *testing.T, source string) []markerComment {
+	file, err := parser.ParseFile(token.NewFileSet(), "test.go", source, parser.ParseComments)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	visitor := newCommentVisitor(file.Comments)
+	ast.Walk(visitor, file)
+
+	for node, markerComments := range visitor.nodeMarkers {
+		if _, ok := node.(*ast.FuncDecl); ok {
+			return markerComments
+		}
+	}
+
+	return nil
+}
+
+func TestMarkerComment_TextWithMultilineRawString(t *testing.T) {
+	markerComments := parseTestMarkerComments(t, `package test
+
+// +marker:template=`+"`"+`Hello {{.Name}},
+//   welcome!
+// +not-a-marker`+"`"+`, Name=test
+// +marker:other
+func Greet() {
+}
+`)
+
+	assert.Len(t, markerComments, 2)
+	assert.Equal(t, "+marker:template=`Hello {{.Name}},\n  welcome!\n+not-a-marker`, Name=test", markerComments[0].Text())
+	assert.Equal(t, "+marker:other", markerComments[1].Text())
+
+	definition, err := MakeDefinition("marker", "", FunctionLevel, &testTemplateMarker{})
+	assert.Nil(t, err)
+
+	value, err := definition.Parse(markerComments[0].Text())
+	assert.Nil(t, err)
+	assert.Equal(t, testTemplateMarker{
+		Template: "Hello {{.Name}},\n  welcome!\n+not-a-marker",
+		Name:     "test",
+	}, value)
+}
+
+func TestNormalizeComment(t *testing.T) {
+	testCases := []struct {
+		Comment  string
+		Expected string
+	}{
+		{"// +marker:name=test", "+marker:name=test"},
+		{"//+marker:name=test  ", "+marker:name=test"},
+		{"// +marker:name=test, \\", "+marker:name=test,"},
+		{"/* +marker:name=test */", "+marker:name=test"},
+		{"/*+marker:name=test*/", "+marker:name=test"},
+		{"// This is a comment", "This is a comment"},
+		{"// +marker:name=test\r", "+marker:name=test"},
+		{"// +marker:name=test, \\\r", "+marker:name=test,"},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.Expected, NormalizeComment(testCase.Comment), testCase.Comment)
+	}
+}
+
+func TestMarkerComment_TextWithBlockComment(t *testing.T) {
+	markerComments := parseTestMarkerComments(t, `package test
+
+/* This is a comment */
+/* +marker:template="block" */
+// +marker:other, \
+//  Name=test
+func Greet() {
+}
+`)
+
+	assert.Len(t, markerComments, 2)
+	assert.Equal(t, "+marker:template=\"block\"", markerComments[0].Text())
+	assert.Equal(t, "/* +marker:template=\"block\" */", markerComments[0].RawText())
+	assert.Equal(t, "+marker:other, Name=test", markerComments[1].Text())
+	assert.Equal(t, "// +marker:other, \\\n//  Name=test", markerComments[1].RawText())
+}
+
+func TestMarkerComment_TextWithMultilineBlockComment(t *testing.T) {
+	markerComments := parseTestMarkerComments(t, `package test
+
+/*
+ * This is a comment
+ * +marker:first=1
+ * +marker:second=2, \
+ *   Name=test
+ */
+/* +marker:third=3 */
+func Greet() {
+}
+`)
+
+	assert.Len(t, markerComments, 3)
+	assert.Equal(t, "+marker:first=1", markerComments[0].Text())
+	assert.Equal(t, "+marker:second=2, Name=test", markerComments[1].Text())
+	assert.Equal(t, "+marker:third=3", markerComments[2].Text())
+}
+
+func TestMarkerValues_Merge(t *testing.T) {
+	base := MarkerValues{
+		"json:name":    {"base"},
+		"validate:min": {1},
+		"tag":          {"a"},
+	}
+	derived := MarkerValues{
+		"json:name": {"derived"},
+		"tag":       {"b", "c"},
+	}
+
+	merged := base.Merge(derived, OverrideStrategy)
+	assert.Equal(t, MarkerValues{
+		"json:name":    {"derived"},
+		"validate:min": {1},
+		"tag":          {"b", "c"},
+	}, merged)
+
+	merged = base.Merge(derived, AppendStrategy)
+	assert.Equal(t, MarkerValues{
+		"json:name":    {"base", "derived"},
+		"validate:min": {1},
+		"tag":          {"a", "b", "c"},
+	}, merged)
+
+	assert.Equal(t, []interface{}{"a"}, base["tag"])
+	assert.Equal(t, MarkerValues{"tag": {"a"}}, MarkerValues(nil).Merge(MarkerValues{"tag": {"a"}}, AppendStrategy))
+}
+
+type testNamingMarker struct {
+	Case string `marker:"case"`
+}
+
+func (m testNamingMarker) Cascade() bool {
+	return true
+}
+
+func TestComputeFieldMarkers(t *testing.T) {
+	typeMarkers := MarkerValues{
+		"json:naming": {testNamingMarker{Case: "snake"}},
+		"deprecated":  {true},
+	}
+
+	assert.Equal(t, MarkerValues{
+		"json:naming": {testNamingMarker{Case: "snake"}},
+		"json:name":   {"id"},
+	}, ComputeFieldMarkers(typeMarkers, MarkerValues{"json:name": {"id"}}))
+
+	assert.Equal(t, MarkerValues{
+		"json:naming": {testNamingMarker{Case: "camel"}},
+	}, ComputeFieldMarkers(typeMarkers, MarkerValues{"json:naming": {testNamingMarker{Case: "camel"}}}))
+
+	assert.Equal(t, MarkerValues{
+		"json:naming": {testNamingMarker{Case: "snake"}},
+	}, ComputeFieldMarkers(typeMarkers, nil))
+
+	assert.Empty(t, ComputeFieldMarkers(nil, nil))
+}
+
+func TestMarkerValues_Clone(t *testing.T) {
+	original := MarkerValues{
+		"collection": {testCollectionMarker{Tags: []string{"a", "b"}, Labels: map[string]int{"x": 1}}},
+		"rules":      {testRulesMarker{Rule: &testRule{Name: "first"}}},
+		"any":        {map[string]interface{}{"list": []interface{}{1, 2}}},
+		"empty":      {nil},
+	}
+
+	clone := original.Clone()
+	assert.Equal(t, original, clone)
+
+	collection := clone["collection"][0].(testCollectionMarker)
+	collection.Tags[0] = "changed"
+	collection.Labels["x"] = 2
+	clone["rules"][0].(testRulesMarker).Rule.Name = "changed"
+	clone["any"][0].(map[string]interface{})["list"].([]interface{})[0] = 3
+	clone["empty"] = append(clone["empty"], "value")
+
+	assert.Equal(t, MarkerValues{
+		"collection": {testCollectionMarker{Tags: []string{"a", "b"}, Labels: map[string]int{"x": 1}}},
+		"rules":      {testRulesMarker{Rule: &testRule{Name: "first"}}},
+		"any":        {map[string]interface{}{"list": []interface{}{1, 2}}},
+		"empty":      {nil},
+	}, original)
+
+	assert.Nil(t, MarkerValues(nil).Clone())
+}
+
+func TestImportMarker_GetCommand(t *testing.T) {
+	definition, err := MakeDefinition(ImportMarkerName, "", ImportLevel, &ImportMarker{})
+	assert.Nil(t, err)
+
+	value, err := definition.Parse(`+import=chrono, Pkg="github.com/procyon-projects/chrono@v1.0.0:chrono-gen, chrono-mock"`)
+	assert.Nil(t, err)
+
+	importMarker := value.(ImportMarker)
+	assert.Equal(t, "github.com/procyon-projects/chrono", importMarker.GetPkgId())
+	assert.Equal(t, "v1.0.0", importMarker.GetPkgVersion())
+	assert.Equal(t, []string{"chrono-gen", "chrono-mock"}, importMarker.GetCommand())
+
+	assert.Equal(t, []string{"chrono-gen"}, ImportMarker{Pkg: "github.com/procyon-projects/chrono:chrono-gen"}.GetCommand())
+	assert.Nil(t, ImportMarker{Pkg: "github.com/procyon-projects/chrono@v1.0.0"}.GetCommand())
+}
+
+func TestMarkerComment_MultilineRawStringNewlines(t *testing.T) {
+	markerComments := parseTestMarkerComments(t, "package test\r\n\r\n"+
+		"// +marker:template=`first\r\n"+
+		"//\r\n"+
+		"//   third`, Name=\"a\\nb\"\r\n"+
+		"func Greet() {\r\n"+
+		"}\r\n")
+
+	assert.Len(t, markerComments, 1)
+
+	definition, err := MakeDefinition("marker", "", FunctionLevel, &testTemplateMarker{})
+	assert.Nil(t, err)
+
+	value, err := definition.Parse(markerComments[0].Text())
+	assert.Nil(t, err)
+	assert.Equal(t, testTemplateMarker{
+		Template: "first\n\n  third",
+		Name:     "a\nb",
+	}, value)
+
+	data, err := MarshalYAML(value)
+	assert.Nil(t, err)
+	assert.Equal(t, "template: \"first\\n\\n  third\"\nName: \"a\\nb\"\n", string(data))
+}
+
+func TestIsMarkerComment(t *testing.T) {
+	testCases := []struct {
+		Comment  string
+		IsMarker bool
+	}{
+		{"// +marker:name=value", true},
+		{"//+marker", true},
+		{"/* +marker */", true},
+		{"// +größe=12", true},
+		{"// see +import for details", false},
+		{"// the +marker:handler marker registers the handler", false},
+		{"// +1 for this approach", false},
+		{"// + marker", false},
+		{"// +", false},
+		{"// marker", false},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.IsMarker, isMarkerComment(testCase.Comment), testCase.Comment)
+	}
+
+	markerComments := parseTestMarkerComments(t, `package test
+
+// Handle handles the requests, see +import for importing the processors.
+// +marker:handler:Path=/books
+func Handle() {
+}
+`)
+
+	assert.Len(t, markerComments, 1)
+	assert.Equal(t, "+marker:handler:Path=/books", markerComments[0].Text())
+}
@@ -1,7 +1,10 @@
 package marker
 
 import (
+	"errors"
 	"fmt"
+	"reflect"
+	"sort"
 	"strings"
 	"sync"
 )
@@ -10,7 +13,23 @@ import (
 type Registry struct {
 	reservedDefinitionMap map[string]*Definition
 	definitionMap         map[string]*Definition
+	implementationMap     map[reflect.Type]reflect.Type
+	parserMap             map[reflect.Type]ArgumentParser
+	// ParenthesizedArguments enables the function call like syntax such as '+name(x=1, y=2)'
+	// which is treated as the same as '+name:x=1,y=2'. It is disabled by default because
+	// the parentheses can be ambiguous for the values containing them.
+	ParenthesizedArguments bool
+	// StrictArguments makes the definitions registered afterwards report the unknown
+	// arguments as errors instead of ignoring them, see Output.Strict.
+	StrictArguments bool
+	// PreferFloat makes the definitions registered afterwards parse the integers in the values
+	// of interface{} arguments as float64 instead of int, see Output.PreferFloat.
+	PreferFloat bool
+	// StrictNames makes the registration of a definition whose name is ambiguous with the
+	// name of a registered definition fail, instead of only adding a warning, see Warnings.
+	StrictNames bool
 
+	warnings []string
 	initOnce sync.Once
 	mu       sync.RWMutex
 }
@@ -32,6 +51,14 @@ func (registry *Registry) initialize() {
 			registry.reservedDefinitionMap = make(map[string]*Definition)
 		}
 
+		if registry.implementationMap == nil {
+			registry.implementationMap = make(map[reflect.Type]reflect.Type)
+		}
+
+		if registry.parserMap == nil {
+			registry.parserMap = make(map[reflect.Type]ArgumentParser)
+		}
+
 		registry.reservedDefinitionMap[ImportMarkerName], _ = MakeDefinition(ImportMarkerName, "", ImportLevel, &ImportMarker{})
 	})
 
@@ -51,6 +78,14 @@ func (registry *Registry) Register(name string, pkgId string, level TargetLevel,
 }
 
 // RegisterWithDefinition registers a new marker with the given definition.
+//
+// The target level of the definition can combine any of the type, field, function,
+// method, function literal and value levels such as TypeLevel|FieldLevel. PackageLevel
+// cannot be combined with the other levels because the package markers are written
+// in the package comments, and ImportLevel is reserved for the import markers.
+//
+// If StrictArguments or PreferFloat is set, a copy of the definition with the policy
+// is registered, and the given definition is not modified.
 func (registry *Registry) RegisterWithDefinition(definition *Definition) error {
 	registry.initialize()
 
@@ -65,6 +100,14 @@ func (registry *Registry) RegisterWithDefinition(definition *Definition) error {
 		return fmt.Errorf("level is not valid for %v, import level cannot be used", definition.Name)
 	}
 
+	if unknownLevel := definition.Level &^ allLevels; unknownLevel != 0 {
+		return fmt.Errorf("level is not valid for %v, unknown level %d", definition.Name, unknownLevel)
+	}
+
+	if definition.Level&PackageLevel == PackageLevel && definition.Level != PackageLevel {
+		return fmt.Errorf("level is not valid for %v, package level cannot be combined with other levels", definition.Name)
+	}
+
 	nameParts := strings.Split(definition.Name, ":")
 	name := nameParts[0]
 
@@ -76,11 +119,276 @@ func (registry *Registry) RegisterWithDefinition(definition *Definition) error {
 		return fmt.Errorf("there is already registered definition : %v", definition.Name)
 	}
 
+	if err := definition.resolveTypes(registry.implementationMap, registry.parserMap); err != nil {
+		return fmt.Errorf("definition %v is not valid : %w", definition.Name, err)
+	}
+
+	if ambiguousNames := registry.findAmbiguousNames(definition); len(ambiguousNames) != 0 {
+		warning := fmt.Sprintf("marker name %v is ambiguous with %v", definition.Name, strings.Join(ambiguousNames, ", "))
+
+		if registry.StrictNames {
+			return errors.New(warning)
+		}
+
+		registry.warnings = append(registry.warnings, warning)
+	}
+
+	// the policies of the registry are applied to a copy of the definition, so that
+	// the given definition is not affected if it is also used elsewhere
+	if registry.StrictArguments || registry.PreferFloat {
+		registeredDefinition := *definition
+		registeredDefinition.Output.Strict = definition.Output.Strict || registry.StrictArguments
+		registeredDefinition.Output.PreferFloat = definition.Output.PreferFloat || registry.PreferFloat
+		definition = &registeredDefinition
+	}
+
 	registry.definitionMap[definition.Name+"#"+definition.PkgId] = definition
 
 	return nil
 }
 
+// findAmbiguousNames returns the sorted names of the registered definitions which can match
+// the same marker as the given definition. For example, '+validation:max=5' can be both the
+// marker 'validation:max' and the marker 'validation' with the argument 'max'.
+func (registry *Registry) findAmbiguousNames(definition *Definition) []string {
+	ambiguousNames := make([]string, 0)
+
+	for _, existing := range registry.definitionMap {
+		if existing.PkgId != definition.PkgId {
+			continue
+		}
+
+		if isAmbiguousName(existing, definition) || isAmbiguousName(definition, existing) {
+			ambiguousNames = append(ambiguousNames, existing.Name)
+		}
+	}
+
+	sort.Strings(ambiguousNames)
+	return ambiguousNames
+}
+
+// isAmbiguousName checks if the name of the longer definition is the name of the shorter
+// definition followed by one of its argument names.
+func isAmbiguousName(shorter *Definition, longer *Definition) bool {
+	if !strings.HasPrefix(longer.Name, shorter.Name+":") {
+		return false
+	}
+
+	argumentName := strings.Split(longer.Name[len(shorter.Name)+1:], ":")[0]
+	_, exists := shorter.Output.Fields[argumentName]
+	return exists
+}
+
+// Warnings returns the warnings found while registering the definitions, such as
+// the names of the definitions which are ambiguous.
+func (registry *Registry) Warnings() []string {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	return append([]string{}, registry.warnings...)
+}
+
+// RegisterImplementation registers the concrete type to be parsed into the arguments
+// whose type is the given interface type. The implementations must be registered
+// before the definitions using them.
+func (registry *Registry) RegisterImplementation(interfaceType reflect.Type, implementationType reflect.Type) error {
+	registry.initialize()
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if interfaceType == nil || interfaceType.Kind() != reflect.Interface {
+		return fmt.Errorf("%v is not an interface type", interfaceType)
+	}
+
+	if implementationType == nil || !implementationType.Implements(interfaceType) {
+		return fmt.Errorf("%v does not implement %v", implementationType, interfaceType)
+	}
+
+	if _, err := GetArgumentTypeInfo(implementationType); err != nil {
+		return fmt.Errorf("%v cannot be used as an implementation : %w", implementationType, err)
+	}
+
+	if _, ok := registry.implementationMap[interfaceType]; ok {
+		return fmt.Errorf("there is already registered implementation for %v", interfaceType)
+	}
+
+	registry.implementationMap[interfaceType] = implementationType
+	return nil
+}
+
+// RegisterParser registers the parser of the arguments whose type is the given type,
+// such as the types of other packages which cannot implement any interface. The parser
+// gets the text of the quoted or the unquoted value. The parsers must be registered
+// before the definitions using them.
+func (registry *Registry) RegisterParser(typ reflect.Type, parser ArgumentParser) error {
+	registry.initialize()
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if typ == nil || parser == nil {
+		return errors.New("type and parser cannot be nil")
+	}
+
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	if _, ok := registry.parserMap[typ]; ok {
+		return fmt.Errorf("there is already registered parser for %v", typ)
+	}
+
+	registry.parserMap[typ] = parser
+	return nil
+}
+
+// Merge copies the definitions, the implementations and the parsers of the other registry into
+// the registry. The definitions registered in both registries must be identical,
+// otherwise an error naming the conflicting marker is returned and nothing is copied.
+func (registry *Registry) Merge(other *Registry) error {
+	if other == nil || other == registry {
+		return nil
+	}
+
+	registry.initialize()
+	other.initialize()
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	other.mu.RLock()
+	defer other.mu.RUnlock()
+
+	for key, definition := range other.definitionMap {
+		if existing, ok := registry.definitionMap[key]; ok && !equalDefinitions(existing, definition) {
+			return fmt.Errorf("there is already registered different definition : %v", definition.Name)
+		}
+	}
+
+	for interfaceType, implementationType := range other.implementationMap {
+		if existing, ok := registry.implementationMap[interfaceType]; ok && existing != implementationType {
+			return fmt.Errorf("there is already registered different implementation for %v", interfaceType)
+		}
+	}
+
+	for typ, parser := range other.parserMap {
+		if existing, ok := registry.parserMap[typ]; ok && reflect.ValueOf(existing).Pointer() != reflect.ValueOf(parser).Pointer() {
+			return fmt.Errorf("there is already registered different parser for %v", typ)
+		}
+	}
+
+	for key, definition := range other.definitionMap {
+		registry.definitionMap[key] = definition
+	}
+
+	for typ, parser := range other.parserMap {
+		registry.parserMap[typ] = parser
+	}
+
+	for interfaceType, implementationType := range other.implementationMap {
+		registry.implementationMap[interfaceType] = implementationType
+	}
+
+	return nil
+}
+
+// Definitions returns the registered definitions sorted by their names and package ids.
+// The import marker definition which is reserved is not included.
+func (registry *Registry) Definitions() []*Definition {
+	registry.initialize()
+
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	definitions := make([]*Definition, 0, len(registry.definitionMap))
+
+	for _, definition := range registry.definitionMap {
+		definitions = append(definitions, definition)
+	}
+
+	sort.Slice(definitions, func(i, j int) bool {
+		if definitions[i].Name != definitions[j].Name {
+			return definitions[i].Name < definitions[j].Name
+		}
+
+		return definitions[i].PkgId < definitions[j].PkgId
+	})
+
+	return definitions
+}
+
+// equalDefinitions reports whether the given definitions have the same name, package,
+// level, output type and arguments. Unlike reflect.DeepEqual, the funcs such as the
+// parsers and the defaults are equal if they are the same function.
+func equalDefinitions(definition *Definition, other *Definition) bool {
+	return equalDefinitionValues(reflect.ValueOf(*definition), reflect.ValueOf(*other))
+}
+
+// reflectTypeType is the type of the reflect.Type fields of the definitions.
+var reflectTypeType = reflect.TypeOf((*reflect.Type)(nil)).Elem()
+
+// equalDefinitionValues reports whether the given values of a definition are deeply equal,
+// the funcs are compared by their pointers and the reflect.Type values by their identities.
+func equalDefinitionValues(value reflect.Value, other reflect.Value) bool {
+	if !value.IsValid() || !other.IsValid() {
+		return value.IsValid() == other.IsValid()
+	}
+
+	if value.Type() != other.Type() {
+		return false
+	}
+
+	if value.Type() == reflectTypeType {
+		return value.IsNil() && other.IsNil() || !value.IsNil() && !other.IsNil() && value.Interface() == other.Interface()
+	}
+
+	switch value.Kind() {
+	case reflect.Func:
+		return value.Pointer() == other.Pointer()
+	case reflect.Ptr, reflect.Interface:
+		if value.IsNil() || other.IsNil() {
+			return value.IsNil() == other.IsNil()
+		}
+
+		return equalDefinitionValues(value.Elem(), other.Elem())
+	case reflect.Struct:
+		for index := 0; index < value.NumField(); index++ {
+			if !equalDefinitionValues(value.Field(index), other.Field(index)) {
+				return false
+			}
+		}
+
+		return true
+	case reflect.Map:
+		if value.IsNil() != other.IsNil() || value.Len() != other.Len() {
+			return false
+		}
+
+		for _, key := range value.MapKeys() {
+			if !equalDefinitionValues(value.MapIndex(key), other.MapIndex(key)) {
+				return false
+			}
+		}
+
+		return true
+	case reflect.Slice, reflect.Array:
+		if value.Len() != other.Len() {
+			return false
+		}
+
+		for index := 0; index < value.Len(); index++ {
+			if !equalDefinitionValues(value.Index(index), other.Index(index)) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return value.Interface() == other.Interface()
+}
+
 // Lookup fetches the definition corresponding to the given name and pkgId.
 func (registry *Registry) Lookup(name string, pkgId string) *Definition {
 	registry.initialize()
@@ -88,21 +396,52 @@ func (registry *Registry) Lookup(name string, pkgId string) *Definition {
 	registry.mu.RLock()
 	defer registry.mu.RUnlock()
 
-	name, anonymousName, _ := splitMarker(name)
+	name = registry.resolveMarkerSyntax(name)
+	_, anonymousName, _ := splitMarker(name)
 	// for syntax-free markers
-	name = strings.Split(name, " ")[0]
+	anonymousName = strings.Split(anonymousName, " ")[0]
 
-	if def, exists := registry.reservedDefinitionMap[anonymousName]; exists {
-		return def
+	// the longest registered name matches, the rest is treated as arguments
+	for _, candidateName := range getMarkerNameCandidates(anonymousName) {
+		if def, exists := registry.reservedDefinitionMap[candidateName]; exists {
+			return def
+		}
+
+		if def, exists := registry.definitionMap[candidateName+"#"+pkgId]; exists {
+			return def
+		}
 	}
 
-	if def, exists := registry.reservedDefinitionMap[name]; exists {
-		return def
+	return nil
+}
+
+// resolveMarkerSyntax converts the parenthesized form of the given marker into
+// the colon form if the parenthesized arguments are enabled.
+func (registry *Registry) resolveMarkerSyntax(marker string) string {
+	if !registry.ParenthesizedArguments {
+		return marker
+	}
+
+	return convertParenthesizedMarker(marker)
+}
+
+// ParseMarker parses the given marker text such as '+validation:max=5' by using
+// the definition registered without a package id, and normalizes and validates the parsed value.
+// It is useful for parsing a marker without loading any package.
+func (registry *Registry) ParseMarker(marker string) (interface{}, error) {
+	marker = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(marker), "//"))
+
+	if len(marker) < 2 || marker[0] != '+' {
+		return nil, fmt.Errorf("marker format is not valid : %s", marker)
 	}
 
-	if def, exists := registry.definitionMap[anonymousName+"#"+pkgId]; exists {
-		return def
+	marker = registry.resolveMarkerSyntax(marker)
+
+	definition := registry.Lookup(marker, "")
+
+	if definition == nil {
+		return nil, fmt.Errorf("there is no registered definition for the marker : %s", marker)
 	}
 
-	return registry.definitionMap[name+"#"+pkgId]
+	return definition.parseAndValidate(marker)
 }
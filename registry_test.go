@@ -1,8 +1,14 @@
 package marker
 
 import (
+	"errors"
+	"fmt"
 	"github.com/stretchr/testify/assert"
+	"net"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 type testMarker struct {
@@ -115,3 +121,372 @@ func TestRegistry_RegisterMarkerWithoutLevel(t *testing.T) {
 	assert.NotNil(t, err)
 	assert.Equal(t, "specify target levels for the definition : marker:test", err.Error())
 }
+
+func TestRegistry_RegisterMarkerWithInvalidLevel(t *testing.T) {
+	testCases := []struct {
+		TargetLevel TargetLevel
+		Error       string
+	}{
+		{ImportLevel | FieldLevel, "level is not valid for marker:test, import level cannot be used"},
+		{PackageLevel | FieldLevel, "level is not valid for marker:test, package level cannot be combined with other levels"},
+		{PackageLevel | TypeLevel, "level is not valid for marker:test, package level cannot be combined with other levels"},
+		{FieldLevel | ValueLevel<<1, "level is not valid for marker:test, unknown level 1024"},
+	}
+
+	registry := NewRegistry()
+
+	for _, testCase := range testCases {
+		err := registry.Register("marker:test", "", testCase.TargetLevel, &testMarker{})
+		assert.NotNil(t, err)
+		assert.Equal(t, testCase.Error, err.Error())
+	}
+
+	assert.Len(t, registry.definitionMap, 0)
+
+	err := registry.Register("marker:test", "", TypeLevel|FieldLevel|MethodLevel, &testMarker{})
+	assert.Nil(t, err)
+}
+
+type testNamedMarker struct {
+	Name string `marker:"Name,optional"`
+}
+
+func TestRegistry_LookupMultiSegmentNames(t *testing.T) {
+	registry := NewRegistry()
+
+	assert.Nil(t, registry.Register("kubebuilder:validation", "", FieldLevel, &testNamedMarker{}))
+	assert.Nil(t, registry.Register("kubebuilder:validation:Required", "", FieldLevel, &testNamedMarker{}))
+	assert.Nil(t, registry.Register("example.com:field", "", FieldLevel, &testNamedMarker{}))
+
+	testCases := []struct {
+		Marker         string
+		DefinitionName string
+		Value          testNamedMarker
+	}{
+		{"+kubebuilder:validation", "kubebuilder:validation", testNamedMarker{}},
+		{"+kubebuilder:validation:Name=first", "kubebuilder:validation", testNamedMarker{Name: "first"}},
+		{"+kubebuilder:validation:Required", "kubebuilder:validation:Required", testNamedMarker{}},
+		{"+kubebuilder:validation:Required:Name=second", "kubebuilder:validation:Required", testNamedMarker{Name: "second"}},
+		{"+example.com:field:Name=third", "example.com:field", testNamedMarker{Name: "third"}},
+	}
+
+	for _, testCase := range testCases {
+		definition := registry.Lookup(testCase.Marker, "")
+
+		if !assert.NotNil(t, definition, testCase.Marker) {
+			continue
+		}
+
+		assert.Equal(t, testCase.DefinitionName, definition.Name)
+
+		value, err := definition.Parse(testCase.Marker)
+		assert.Nil(t, err, testCase.Marker)
+		assert.Equal(t, testCase.Value, value, testCase.Marker)
+	}
+
+	assert.Nil(t, registry.Lookup("+kubebuilder", ""))
+	assert.Nil(t, registry.Lookup("+example.com", ""))
+}
+
+type testValidationMarker struct {
+	Max int `marker:"max"`
+}
+
+func (m testValidationMarker) Validate() error {
+	if m.Max < 0 {
+		return errors.New("'max' argument cannot be negative")
+	}
+
+	return nil
+}
+
+func TestRegistry_ParseMarker(t *testing.T) {
+	registry := NewRegistry()
+	assert.Nil(t, registry.Register("validation", "", FieldLevel, &testValidationMarker{}))
+
+	value, err := registry.ParseMarker("+validation:max=5")
+	assert.Nil(t, err)
+	assert.Equal(t, testValidationMarker{Max: 5}, value)
+
+	value, err = registry.ParseMarker("// +validation:max=7")
+	assert.Nil(t, err)
+	assert.Equal(t, testValidationMarker{Max: 7}, value)
+
+	value, err = registry.ParseMarker("+validation:max=-1")
+	assert.Nil(t, value)
+	assert.NotNil(t, err)
+	assert.Equal(t, "'max' argument cannot be negative", err.Error())
+
+	value, err = registry.ParseMarker("+validation:min=1")
+	assert.Nil(t, value)
+	assert.NotNil(t, err)
+	assert.Equal(t, "[missing argument \"max\"]", err.Error())
+
+	value, err = registry.ParseMarker("+unknown:max=1")
+	assert.Nil(t, value)
+	assert.NotNil(t, err)
+	assert.Equal(t, "there is no registered definition for the marker : +unknown:max=1", err.Error())
+
+	value, err = registry.ParseMarker("validation:max=1")
+	assert.Nil(t, value)
+	assert.NotNil(t, err)
+}
+
+type testNormalizableMarker struct {
+	Name  string `marker:"name"`
+	Alias string `marker:"alias,optional"`
+}
+
+func (m *testNormalizableMarker) Normalize() error {
+	m.Name = strings.ToLower(m.Name)
+
+	if m.Alias == "" {
+		m.Alias = m.Name
+	}
+
+	return nil
+}
+
+func (m testNormalizableMarker) Validate() error {
+	if m.Name != strings.ToLower(m.Name) {
+		return errors.New("'name' argument must be normalized before validation")
+	}
+
+	return nil
+}
+
+func TestRegistry_ParseMarkerWithNormalization(t *testing.T) {
+	registry := NewRegistry()
+	assert.Nil(t, registry.Register("json", "", FieldLevel, &testNormalizableMarker{}))
+
+	value, err := registry.ParseMarker("+json:name=UserName")
+	assert.Nil(t, err)
+	assert.Equal(t, testNormalizableMarker{Name: "username", Alias: "username"}, value)
+
+	value, err = registry.ParseMarker("+json:name=UserName,alias=user")
+	assert.Nil(t, err)
+	assert.Equal(t, testNormalizableMarker{Name: "username", Alias: "user"}, value)
+}
+
+type testValidator interface {
+	Match(value string) bool
+}
+
+type testPrefixValidator string
+
+func (v testPrefixValidator) Match(value string) bool {
+	return strings.HasPrefix(value, string(v))
+}
+
+type testValidatorMarker struct {
+	Validator  testValidator   `marker:"validator"`
+	Validators []testValidator `marker:"validators,optional"`
+}
+
+func TestRegistry_RegisterImplementation(t *testing.T) {
+	validatorType := reflect.TypeOf((*testValidator)(nil)).Elem()
+
+	registry := NewRegistry()
+	err := registry.Register("validate", "", FieldLevel, &testValidatorMarker{})
+	assert.NotNil(t, err)
+	assert.Equal(t, "definition validate is not valid : argument \"validator\" cannot be resolved : there is no registered implementation for the interface type marker.testValidator", err.Error())
+
+	assert.NotNil(t, registry.RegisterImplementation(reflect.TypeOf(""), reflect.TypeOf(testPrefixValidator(""))))
+	assert.NotNil(t, registry.RegisterImplementation(validatorType, reflect.TypeOf("")))
+	assert.Nil(t, registry.RegisterImplementation(validatorType, reflect.TypeOf(testPrefixValidator(""))))
+	assert.NotNil(t, registry.RegisterImplementation(validatorType, reflect.TypeOf(testPrefixValidator(""))))
+	assert.Nil(t, registry.Register("validate", "", FieldLevel, &testValidatorMarker{}))
+
+	value, err := registry.ParseMarker(`+validate:validator="user",validators={"a","b"}`)
+	assert.Nil(t, err)
+	assert.Equal(t, testValidatorMarker{
+		Validator:  testPrefixValidator("user"),
+		Validators: []testValidator{testPrefixValidator("a"), testPrefixValidator("b")},
+	}, value)
+}
+
+type testRangeMarker struct {
+	Min int `marker:"Min"`
+	Max int `marker:"Max"`
+}
+
+func TestRegistry_ParseMarkerWithParenthesizedArguments(t *testing.T) {
+	registry := NewRegistry()
+	assert.Nil(t, registry.Register("validation:range", "", FieldLevel, &testRangeMarker{}))
+
+	_, err := registry.ParseMarker("+validation:range(Min=1, Max=5)")
+	assert.NotNil(t, err)
+
+	registry.ParenthesizedArguments = true
+
+	value, err := registry.ParseMarker("+validation:range(Min=1, Max=5)")
+	assert.Nil(t, err)
+	assert.Equal(t, testRangeMarker{Min: 1, Max: 5}, value)
+
+	value, err = registry.ParseMarker("+validation:range:Min=2,Max=3")
+	assert.Nil(t, err)
+	assert.Equal(t, testRangeMarker{Min: 2, Max: 3}, value)
+}
+
+func TestConvertParenthesizedMarker(t *testing.T) {
+	testCases := []struct {
+		Marker   string
+		Expected string
+	}{
+		{"+marker(x=1, y=2)", "+marker:x=1, y=2"},
+		{"+marker:name(x=\"(a)\")", "+marker:name:x=\"(a)\""},
+		{"+marker()", "+marker"},
+		{"+marker:x=(1)", "+marker:x=(1)"},
+		{"+marker:x=1", "+marker:x=1"},
+		{"+(x=1)", "+(x=1)"},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.Expected, convertParenthesizedMarker(testCase.Marker), testCase.Marker)
+	}
+}
+
+func TestRegistry_Merge(t *testing.T) {
+	registry := NewRegistry()
+	assert.Nil(t, registry.Register("marker:type-level", "", TypeLevel, &testTypeLevelMarker{}))
+
+	plugin := NewRegistry()
+	assert.Nil(t, plugin.Register("marker:type-level", "", TypeLevel, &testTypeLevelMarker{}))
+	assert.Nil(t, plugin.Register("marker:function-level", "", FunctionLevel, &testFunctionLevelMarker{}))
+
+	assert.Nil(t, registry.Merge(plugin))
+	assert.NotNil(t, registry.Lookup("+marker:type-level", ""))
+	assert.NotNil(t, registry.Lookup("+marker:function-level", ""))
+
+	conflicting := NewRegistry()
+	assert.Nil(t, conflicting.Register("marker:function-level", "", MethodLevel, &testFunctionLevelMarker{}))
+	assert.Nil(t, conflicting.Register("marker:other", "", FieldLevel, &testMarker{}))
+
+	err := registry.Merge(conflicting)
+	assert.NotNil(t, err)
+	assert.Equal(t, "there is already registered different definition : marker:function-level", err.Error())
+	assert.Nil(t, registry.Lookup("+marker:other", ""))
+	assert.Equal(t, FunctionLevel, registry.Lookup("+marker:function-level", "").Level)
+}
+
+type testNetworkMarker struct {
+	Address net.IP   `marker:"address"`
+	Gateway *net.IP  `marker:"gateway,optional"`
+	Dns     []net.IP `marker:"dns,optional"`
+}
+
+func TestRegistry_RegisterParser(t *testing.T) {
+	registry := NewRegistry()
+	err := registry.RegisterParser(reflect.TypeOf(net.IP{}), func(text string) (interface{}, error) {
+		ip := net.ParseIP(text)
+
+		if ip == nil {
+			return nil, fmt.Errorf("%q is not a valid IP address", text)
+		}
+
+		return ip, nil
+	})
+	assert.Nil(t, err)
+	assert.NotNil(t, registry.RegisterParser(reflect.TypeOf(net.IP{}), func(text string) (interface{}, error) {
+		return nil, nil
+	}))
+
+	assert.Nil(t, registry.Register("network", "", FieldLevel, &testNetworkMarker{}))
+
+	gateway := net.ParseIP("10.0.0.1")
+	value, err := registry.ParseMarker(`+network:address=192.168.1.10,gateway="10.0.0.1",dns={8.8.8.8,"1.1.1.1"}`)
+	assert.Nil(t, err)
+	assert.Equal(t, testNetworkMarker{
+		Address: net.ParseIP("192.168.1.10"),
+		Gateway: &gateway,
+		Dns:     []net.IP{net.ParseIP("8.8.8.8"), net.ParseIP("1.1.1.1")},
+	}, value)
+
+	_, err = registry.ParseMarker(`+network:address=invalid`)
+	assert.NotNil(t, err)
+	assert.Equal(t, "[unable to parse net.IP: \"invalid\" is not a valid IP address]", err.Error())
+}
+
+func TestRegistry_DefinitionsWithMetadata(t *testing.T) {
+	registry := NewRegistry()
+
+	definition, err := MakeDefinition("validation", "", FieldLevel, &testValidationMarker{})
+	assert.Nil(t, err)
+
+	definition.Metadata = map[string]string{
+		"category": "validation",
+		"docs":     "https://github.com/procyon-projects/marker",
+	}
+
+	err = registry.RegisterWithDefinition(definition)
+	assert.Nil(t, err)
+	err = registry.Register("marker:named", "", TypeLevel, &testNamedMarker{})
+	assert.Nil(t, err)
+
+	definitions := registry.Definitions()
+	assert.Len(t, definitions, 2)
+	assert.Equal(t, "marker:named", definitions[0].Name)
+	assert.Equal(t, "validation", definitions[1].Name)
+
+	category, exists := definitions[1].GetMetadata("category")
+	assert.True(t, exists)
+	assert.Equal(t, "validation", category)
+
+	_, exists = definitions[1].GetMetadata("help")
+	assert.False(t, exists)
+
+	_, exists = definitions[0].GetMetadata("category")
+	assert.False(t, exists)
+}
+
+func TestRegistry_RegisterAmbiguousNames(t *testing.T) {
+	registry := NewRegistry()
+
+	assert.Nil(t, registry.Register("validation", "", FieldLevel, &testValidationMarker{}))
+	assert.Nil(t, registry.Register("validation:required", "", FieldLevel, &testNamedMarker{}))
+	assert.Empty(t, registry.Warnings())
+
+	assert.Nil(t, registry.Register("validation:max", "", FieldLevel, &testNamedMarker{}))
+	assert.Equal(t, []string{"marker name validation:max is ambiguous with validation"}, registry.Warnings())
+
+	registry = NewRegistry()
+	registry.StrictNames = true
+
+	assert.Nil(t, registry.Register("validation:max:inclusive", "", FieldLevel, &testNamedMarker{}))
+	assert.Nil(t, registry.Register("other", "", FieldLevel, &testValidationMarker{}))
+
+	err := registry.Register("validation", "", FieldLevel, &testValidationMarker{})
+	assert.NotNil(t, err)
+	assert.Equal(t, "marker name validation is ambiguous with validation:max:inclusive", err.Error())
+	assert.Nil(t, registry.Lookup("+validation", ""))
+	assert.Empty(t, registry.Warnings())
+}
+
+type testScheduleMarker struct {
+	At time.Time `marker:"at"`
+}
+
+func TestRegistry_MergeDefinitionsWithFuncs(t *testing.T) {
+	registry := NewRegistry()
+	assert.Nil(t, registry.Register("schedule", "", TypeLevel, &testScheduleMarker{}))
+
+	plugin := NewRegistry()
+	assert.Nil(t, plugin.Register("schedule", "", TypeLevel, &testScheduleMarker{}))
+
+	assert.Nil(t, registry.Merge(plugin))
+	assert.NotNil(t, registry.Lookup("+schedule", ""))
+
+	conflicting := NewRegistry()
+	definition, err := MakeDefinition("schedule", "", TypeLevel, &testScheduleMarker{})
+	assert.Nil(t, err)
+
+	definition.Defaults = func(out interface{}) {
+		out.(*testScheduleMarker).At = time.Unix(0, 0)
+	}
+
+	assert.Nil(t, conflicting.RegisterWithDefinition(definition))
+
+	err = registry.Merge(conflicting)
+	assert.NotNil(t, err)
+	assert.Equal(t, "there is already registered different definition : schedule", err.Error())
+}
@@ -1,9 +1,18 @@
 package marker
 
-import "fmt"
+import (
+	"fmt"
+	"go/constant"
+	"unicode/utf8"
+)
 
 const Whitespace = 1<<'\t' | 1<<'\r' | 1<<' '
 
+// defaultTerminators are the characters terminating an unquoted value by default.
+var defaultTerminators = []rune{',', ';', ':', '}'}
+
+// Token kinds returned by Scan and NextToken. The other tokens such as the delimiters
+// ',', '=' and '{' are returned as their characters.
 const (
 	EOF = -(iota + 1)
 	Identifier
@@ -20,8 +29,31 @@ type Scanner struct {
 
 	errorCount    int
 	ErrorCallback func(scanner *Scanner, message string)
+	// SkipContinuations makes the scanner skip the artifacts of the joined comment
+	// lines such as the newlines, the continuation characters '\\' at the end of
+	// the lines and the comment characters '//' at the beginning of the lines
+	// in addition to the whitespaces.
+	SkipContinuations bool
+	// Terminators are the characters terminating an unquoted value in addition to EOF.
+	// If it is nil, the default terminators ',', ';', ':' and '}' are used. It is meant
+	// for the scanners created to parse the values with ArgumentTypeInfo.Parse directly,
+	// the markers parsed by the definitions always use the default terminators.
+	Terminators []rune
+	// PreferFloat makes the integers such as 10 in the values whose types are inferred,
+	// such as the values of interface{} arguments, be parsed as float64 instead of int.
+	PreferFloat bool
+
+	// resolveConstant resolves the names of the Go constants given as the numeric values.
+	resolveConstant constantResolver
+	// columnOffset is the number of the characters before the source in the marker such as
+	// '+name:' for the arguments, so that the reported columns are relative to the marker.
+	columnOffset int
 }
 
+// constantResolver returns the value of the Go constant with the given name such as
+// 'MaxSize' or 'math.MaxInt32', and whether the constant exists.
+type constantResolver func(name string) (constant.Value, bool)
+
 func NewScanner(source string) *Scanner {
 	return &Scanner{
 		source:             []byte(source),
@@ -74,7 +106,7 @@ func (scanner *Scanner) Expect(expected rune, description string) bool {
 
 func (scanner *Scanner) Reset() {
 	scanner.searchIndex = 0
-	scanner.character = rune(scanner.source[0])
+	scanner.character = scanner.characterAt(0)
 	scanner.tokenStartPosition = 0
 	scanner.tokenEndPosition = 0
 }
@@ -86,20 +118,37 @@ func (scanner *Scanner) SetSearchIndex(searchIndex int) {
 	}
 
 	scanner.searchIndex = searchIndex
-	scanner.character = rune(scanner.source[searchIndex])
+	scanner.character = scanner.characterAt(searchIndex)
 }
 
+// Next moves to the next character and returns it. The characters are
+// decoded as UTF-8, so the search index moves by the width of the current character.
 func (scanner *Scanner) Next() rune {
-	scanner.searchIndex++
+	if scanner.searchIndex >= 0 && scanner.searchIndex < scanner.SourceLength() {
+		_, width := utf8.DecodeRune(scanner.source[scanner.searchIndex:])
+		scanner.searchIndex += width
+	} else {
+		scanner.searchIndex++
+	}
 
 	if scanner.searchIndex >= scanner.SourceLength() {
 		return EOF
 	}
 
-	return rune(scanner.source[scanner.searchIndex])
+	return scanner.characterAt(scanner.searchIndex)
+}
+
+// characterAt returns the UTF-8 encoded character starting at the given index.
+func (scanner *Scanner) characterAt(index int) rune {
+	character, _ := utf8.DecodeRune(scanner.source[index:])
+	return character
 }
 
 func (scanner *Scanner) SkipWhitespaces() rune {
+	if scanner.SkipContinuations {
+		return scanner.SkipWhitespacesAndContinuations()
+	}
+
 	character := scanner.Peek()
 
 	for Whitespace&(1<<uint(character)) != 0 {
@@ -110,6 +159,65 @@ func (scanner *Scanner) SkipWhitespaces() rune {
 	return character
 }
 
+// SkipWhitespacesAndContinuations skips the whitespaces, the newlines, the continuation
+// characters '\\' followed by a newline and the comment characters '//' at the beginning
+// of a line, so that the joined comment lines are scanned as a single line.
+func (scanner *Scanner) SkipWhitespacesAndContinuations() rune {
+	character := scanner.Peek()
+	lineStart := false
+
+	for {
+		if Whitespace&(1<<uint(character)) != 0 {
+			character = scanner.Next()
+		} else if character == '\n' {
+			lineStart = true
+			character = scanner.Next()
+		} else if character == '\\' && scanner.isLineEnd(scanner.searchIndex+1) {
+			character = scanner.Next()
+		} else if character == '/' && lineStart && scanner.searchIndex+1 < scanner.SourceLength() && scanner.source[scanner.searchIndex+1] == '/' {
+			scanner.Next()
+			character = scanner.Next()
+			lineStart = false
+		} else {
+			break
+		}
+	}
+
+	scanner.character = character
+	return character
+}
+
+// IsTerminator reports whether the given character terminates an unquoted value.
+func (scanner *Scanner) IsTerminator(character rune) bool {
+	if character == EOF {
+		return true
+	}
+
+	terminators := scanner.Terminators
+
+	if terminators == nil {
+		terminators = defaultTerminators
+	}
+
+	for _, terminator := range terminators {
+		if character == terminator {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isLineEnd reports whether the given index is the end of a line, the carriage
+// returns before the newline are ignored.
+func (scanner *Scanner) isLineEnd(index int) bool {
+	for index < scanner.SourceLength() && scanner.source[index] == '\r' {
+		index++
+	}
+
+	return index < scanner.SourceLength() && scanner.source[index] == '\n'
+}
+
 func (scanner *Scanner) Scan() rune {
 	character := scanner.SkipWhitespaces()
 
@@ -147,7 +255,8 @@ func (scanner *Scanner) ScanNumber() rune {
 
 	character := scanner.SkipWhitespaces()
 
-	for IsDecimal(character) {
+	// the digits can be separated by underscores such as 1_000_000
+	for IsDecimal(character) || character == '_' {
 		character = scanner.Next()
 	}
 
@@ -177,11 +286,28 @@ func (scanner *Scanner) ScanString(quote rune) (len int) {
 	character := scanner.Next()
 
 	for character != quote {
-		if character == '\n' || character < 0 {
-			scanner.AddError(fmt.Sprintf("'%c' is missing", quote))
+		if (character == '\n' && quote != '`') || character < 0 {
+			scanner.tokenEndPosition = scanner.searchIndex
+
+			if scanner.tokenEndPosition > scanner.SourceLength() {
+				scanner.tokenEndPosition = scanner.SourceLength()
+			}
+
+			scanner.character = character
+			scanner.AddError(fmt.Sprintf("unterminated string literal at column %d, '%c' is missing", scanner.columnOffset+scanner.tokenStartPosition+1, quote))
 			return
 		}
 
+		// skip the escaped character so that an escaped quote does not terminate the string
+		if character == '\\' && quote != '`' {
+			character = scanner.Next()
+			len++
+
+			if character < 0 {
+				continue
+			}
+		}
+
 		character = scanner.Next()
 		len++
 	}
@@ -192,6 +318,39 @@ func (scanner *Scanner) ScanString(quote rune) (len int) {
 	return
 }
 
+// scanConstantName scans the name of a constant such as 'MaxSize' or the qualified
+// name of a constant in an imported package such as 'math.MaxInt32'.
+func (scanner *Scanner) scanConstantName() string {
+	scanner.Scan()
+	name := scanner.Token()
+
+	if scanner.Peek() == '.' {
+		scanner.Scan()
+
+		if scanner.Scan() != Identifier {
+			return name + "."
+		}
+
+		name += "." + scanner.Token()
+	}
+
+	return name
+}
+
+// NextToken scans the next token, and returns its kind and its text. The kind is one of
+// Identifier, Integer, String and EOF, or the character of the token such as ','. It makes
+// it possible to implement custom grammars for the argument values over the same lexer.
+// The text of a string token keeps its quotes, and the text is empty at the end.
+func (scanner *Scanner) NextToken() (rune, string) {
+	kind := scanner.Scan()
+
+	if kind == EOF {
+		return EOF, ""
+	}
+
+	return kind, scanner.Token()
+}
+
 func (scanner *Scanner) Token() string {
 	if scanner.tokenStartPosition < 0 {
 		return ""
@@ -1,7 +1,10 @@
 package marker
 
 import (
+	"fmt"
 	"github.com/stretchr/testify/assert"
+	"reflect"
+	"strconv"
 	"testing"
 )
 
@@ -147,3 +150,186 @@ func TestScanner_AllScans(t *testing.T) {
 	current = scanner.Scan()
 	assert.Equal(t, EOF, int(current))
 }
+
+func TestScanner_ScanUnterminatedString(t *testing.T) {
+	var messages []string
+
+	scanner := NewScanner("key=\"oops")
+	scanner.ErrorCallback = func(scanner *Scanner, message string) {
+		messages = append(messages, message)
+	}
+
+	current := scanner.Scan()
+	assert.Equal(t, Identifier, int(current))
+
+	current = scanner.Scan()
+	assert.Equal(t, '=', current)
+
+	current = scanner.Scan()
+	assert.Equal(t, String, int(current))
+	assert.Equal(t, "\"oops", scanner.Token())
+
+	current = scanner.Scan()
+	assert.Equal(t, EOF, int(current))
+
+	assert.Equal(t, 1, scanner.ErrorCount())
+	assert.Equal(t, []string{"unterminated string literal at column 5, '\"' is missing"}, messages)
+}
+
+func TestScanner_SkipContinuations(t *testing.T) {
+	source := "key1=123, \\\r\n// key2=`a\nb`,\n\tkey3=\"hello\""
+	expected := []struct {
+		Token rune
+		Text  string
+	}{
+		{Identifier, "key1"},
+		{'=', "="},
+		{Integer, "123"},
+		{',', ","},
+		{Identifier, "key2"},
+		{'=', "="},
+		{String, "`a\nb`"},
+		{',', ","},
+		{Identifier, "key3"},
+		{'=', "="},
+		{String, "\"hello\""},
+	}
+
+	scanner := NewScanner(source)
+	scanner.SkipContinuations = true
+
+	for _, token := range expected {
+		current := scanner.Scan()
+		assert.Equal(t, token.Token, current)
+		assert.Equal(t, token.Text, scanner.Token())
+	}
+
+	assert.Equal(t, EOF, int(scanner.Scan()))
+	assert.Equal(t, 0, scanner.ErrorCount())
+
+	scanner = NewScanner(source)
+	scanner.Scan()
+	scanner.Scan()
+	scanner.Scan()
+	scanner.Scan()
+	assert.Equal(t, '\\', scanner.Scan())
+}
+
+func TestScanner_Terminators(t *testing.T) {
+	scanner := NewScanner("x")
+	assert.True(t, scanner.IsTerminator(','))
+	assert.True(t, scanner.IsTerminator(':'))
+	assert.True(t, scanner.IsTerminator(EOF))
+	assert.False(t, scanner.IsTerminator('&'))
+
+	var values []string
+	typeInfo := ArgumentTypeInfo{ActualType: StringType}
+
+	scanner = NewScanner("a:b&c,d")
+	scanner.Terminators = []rune{'&'}
+
+	for {
+		var value string
+		assert.Nil(t, typeInfo.Parse(scanner, reflect.ValueOf(&value).Elem()))
+		values = append(values, value)
+
+		if scanner.Scan() == EOF {
+			break
+		}
+	}
+
+	assert.Equal(t, []string{"a:b", "c,d"}, values)
+
+	var items []string
+	sliceTypeInfo, err := GetArgumentTypeInfo(reflect.TypeOf(items))
+	assert.Nil(t, err)
+
+	scanner = NewScanner("a;b:c&d")
+	scanner.Terminators = []rune{';', '&'}
+	assert.Nil(t, sliceTypeInfo.Parse(scanner, reflect.ValueOf(&items).Elem()))
+	assert.Equal(t, []string{"a", "b:c"}, items)
+	assert.Equal(t, '&', scanner.SkipWhitespaces())
+}
+
+func TestScanner_ScanUnicodeIdentifier(t *testing.T) {
+	scanner := NewScanner("größe=12,名前=\"değer\"")
+
+	current := scanner.Scan()
+	assert.Equal(t, Identifier, int(current))
+	assert.Equal(t, "größe", scanner.Token())
+
+	assert.Equal(t, '=', scanner.Scan())
+	assert.Equal(t, Integer, int(scanner.Scan()))
+	assert.Equal(t, ',', scanner.Scan())
+
+	current = scanner.Scan()
+	assert.Equal(t, Identifier, int(current))
+	assert.Equal(t, "名前", scanner.Token())
+
+	assert.Equal(t, '=', scanner.Scan())
+	assert.Equal(t, String, int(scanner.Scan()))
+	assert.Equal(t, "\"değer\"", scanner.Token())
+	assert.Equal(t, EOF, int(scanner.Scan()))
+}
+
+func TestScanner_NextToken(t *testing.T) {
+	scanner := NewScanner(`size=10, name="test"`)
+
+	var tokens []string
+
+	for kind, text := scanner.NextToken(); kind != EOF; kind, text = scanner.NextToken() {
+		tokens = append(tokens, text)
+	}
+
+	assert.Equal(t, []string{"size", "=", "10", ",", "name", "=", `"test"`}, tokens)
+
+	kind, text := scanner.NextToken()
+	assert.Equal(t, EOF, int(kind))
+	assert.Equal(t, "", text)
+}
+
+type exampleSpan struct {
+	Min int
+	Max int
+}
+
+type examplePageMarker struct {
+	Span exampleSpan `marker:"span"`
+}
+
+// parseExampleSpan implements the grammar 'min..max' such as '1..10'.
+func parseExampleSpan(text string) (interface{}, error) {
+	scanner := NewScanner(text)
+	span := exampleSpan{}
+
+	for index, expected := range []rune{Integer, '.', '.', Integer, EOF} {
+		kind, token := scanner.NextToken()
+
+		if kind != expected {
+			return nil, fmt.Errorf("unexpected token %q at %d", token, index)
+		}
+
+		if kind == Integer && index == 0 {
+			span.Min, _ = strconv.Atoi(token)
+		} else if kind == Integer {
+			span.Max, _ = strconv.Atoi(token)
+		}
+	}
+
+	return span, nil
+}
+
+func ExampleScanner_NextToken() {
+	registry := NewRegistry()
+	_ = registry.RegisterParser(reflect.TypeOf(exampleSpan{}), parseExampleSpan)
+	_ = registry.Register("page", "", TypeLevel, &examplePageMarker{})
+
+	value, err := registry.ParseMarker("+page:span=1..10")
+	fmt.Println(value, err)
+
+	_, err = registry.ParseMarker("+page:span=1..x")
+	fmt.Println(err)
+	// Output:
+	// {{1 10}} <nil>
+	// [unable to parse marker.exampleSpan: unexpected token "x" at 3]
+}
@@ -1 +1,26 @@
 package package2
+
+type Book struct {
+	// Title is the title of the book.
+	// +deprecated Use Name instead
+	// It cannot be empty.
+	Title  string `json:"title" marker:"+marker:field-level:Name=title"`
+	Author string `json:"author"`
+}
+
+// +marker:handler:Path=/books
+var ListBooks = func() []Book {
+	return nil
+}
+
+func RegisterHandlers(register func(path string, handler func())) {
+	// +marker:handler:Path=/authors
+	listAuthors := func() {
+	}
+
+	register("/authors", listAuthors)
+
+	// +marker:handler:Path=/health
+	register("/health", func() {
+	})
+}
@@ -0,0 +1,5 @@
+package package2
+
+// +marker:handler:Path=/test
+var testHandler = func() {
+}
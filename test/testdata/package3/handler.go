@@ -0,0 +1,6 @@
+package package3
+
+// +marker:handler:Path=/orders
+var ListOrders = func() []string {
+	return nil
+}
@@ -0,0 +1,8 @@
+// +marker:package-level
+package package3
+
+// Order is an order of books.
+type Order struct {
+	Id    int
+	Books []string
+}
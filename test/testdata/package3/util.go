@@ -0,0 +1,5 @@
+package package3
+
+func countOrders(orders []Order) int {
+	return len(orders)
+}
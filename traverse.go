@@ -2,15 +2,23 @@ package marker
 
 import (
 	"errors"
+	"fmt"
 	"go/ast"
 	"go/token"
 	"golang.org/x/tools/go/packages"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 )
 
 type Kind int
 
+func (kind Kind) String() string {
+	return kindText[kind]
+}
+
 const (
 	AnyKind Kind = iota
 	Object
@@ -25,6 +33,20 @@ const (
 	UserDefined
 )
 
+var kindText = map[Kind]string{
+	AnyKind:     "AnyKind",
+	Object:      "Object",
+	Array:       "Array",
+	Chan:        "Chan",
+	Map:         "Map",
+	Ptr:         "Ptr",
+	Variadic:    "Variadic",
+	Function:    "Function",
+	Interface:   "Interface",
+	Struct:      "Struct",
+	UserDefined: "UserDefined",
+}
+
 type Type interface {
 	Kind() Kind
 }
@@ -53,9 +75,47 @@ type TypeInfo struct {
 
 type FileCallback func(file *File, err error)
 
+// FunctionCallback is called for each function, or it is called with an error.
+type FunctionCallback func(function *FunctionType, err error)
+
+// Position is the location of a node or a marker in a source file.
 type Position struct {
-	Line   int
-	Column int
+	file   string
+	line   int
+	column int
+}
+
+// File returns the name of the file including its path.
+func (position Position) File() string {
+	return position.file
+}
+
+// Line returns the line number starting at 1.
+func (position Position) Line() int {
+	return position.line
+}
+
+// Column returns the column number in bytes starting at 1.
+func (position Position) Column() int {
+	return position.column
+}
+
+// String returns the position in the form of file:line:column, the file name or
+// the whole position is omitted if it is unknown.
+func (position Position) String() string {
+	if position.line <= 0 {
+		if position.file == "" {
+			return "-"
+		}
+
+		return position.file
+	}
+
+	if position.file == "" {
+		return fmt.Sprintf("%d:%d", position.line, position.column)
+	}
+
+	return fmt.Sprintf("%s:%d:%d", position.file, position.line, position.column)
 }
 
 type PackageInfo struct {
@@ -174,6 +234,15 @@ func (function FunctionType) Kind() Kind {
 	return Function
 }
 
+// Node returns the function declaration which the markers of the function are associated with.
+func (function FunctionType) Node() ast.Node {
+	if function.RawFuncDecl == nil {
+		return nil
+	}
+
+	return function.RawFuncDecl
+}
+
 type Field struct {
 	Name       string
 	IsExported bool
@@ -186,6 +255,51 @@ type Field struct {
 	RawField   *ast.Field
 }
 
+// Node returns the AST field which the markers of the field are associated with.
+func (field Field) Node() ast.Node {
+	if field.RawField == nil {
+		return nil
+	}
+
+	return field.RawField
+}
+
+// Tag returns the parsed struct tag of the field.
+func (field Field) Tag() reflect.StructTag {
+	if field.RawField == nil || field.RawField.Tag == nil {
+		return ""
+	}
+
+	tag, err := strconv.Unquote(field.RawField.Tag.Value)
+
+	if err != nil {
+		return ""
+	}
+
+	return reflect.StructTag(tag)
+}
+
+// Doc returns the documentation of the field without the marker comments.
+func (field Field) Doc() string {
+	if field.RawField == nil || field.RawField.Doc == nil {
+		return ""
+	}
+
+	docCommentGroup := &ast.CommentGroup{}
+	hasContinuation := false
+
+	for _, comment := range field.RawField.Doc.List {
+		isMarker := isMarkerComment(comment.Text) || hasContinuation
+		hasContinuation = isMarker && hasContinuationCharacter(comment.Text)
+
+		if !isMarker {
+			docCommentGroup.List = append(docCommentGroup.List, comment)
+		}
+	}
+
+	return docCommentGroup.Text()
+}
+
 type Method struct {
 	Name         string
 	IsExported   bool
@@ -201,6 +315,26 @@ type Method struct {
 	RawFuncType  *ast.FuncType
 }
 
+// Node returns the AST node which the markers of the method are associated with,
+// it is the function declaration of a struct method or the field of an interface method.
+func (method Method) Node() ast.Node {
+	if method.RawFuncDecl != nil {
+		return method.RawFuncDecl
+	}
+
+	if method.RawField != nil {
+		return method.RawField
+	}
+
+	return nil
+}
+
+// ReceiverType returns the name of the receiver type of the struct method without its
+// pointer such as 'Book' for 'func (b *Book) Title()'. It is empty for the interface methods.
+func (method Method) ReceiverType() string {
+	return ReceiverTypeName(method.RawFuncDecl)
+}
+
 type StructType struct {
 	Name        string
 	IsExported  bool
@@ -218,6 +352,11 @@ func (typ StructType) Kind() Kind {
 	return Struct
 }
 
+// Node returns the type specification which the markers of the struct type are associated with.
+func (typ StructType) Node() ast.Node {
+	return typeSpecNode(typ.RawTypeSpec)
+}
+
 type UserDefinedType struct {
 	Name        string
 	IsExported  bool
@@ -235,6 +374,11 @@ func (typ UserDefinedType) Kind() Kind {
 	return UserDefined
 }
 
+// Node returns the type specification which the markers of the type are associated with.
+func (typ UserDefinedType) Node() ast.Node {
+	return typeSpecNode(typ.RawTypeSpec)
+}
+
 type InterfaceType struct {
 	Name        string
 	IsExported  bool
@@ -251,6 +395,20 @@ func (typ InterfaceType) Kind() Kind {
 	return Interface
 }
 
+// Node returns the type specification which the markers of the interface type are associated with.
+func (typ InterfaceType) Node() ast.Node {
+	return typeSpecNode(typ.RawTypeSpec)
+}
+
+// typeSpecNode returns the given type specification as a node, or nil if it does not exist.
+func typeSpecNode(typeSpec *ast.TypeSpec) ast.Node {
+	if typeSpec == nil {
+		return nil
+	}
+
+	return typeSpec
+}
+
 type AnonymousStructType struct {
 	Fields []Field
 }
@@ -267,13 +425,19 @@ func (typ VariadicType) Kind() Kind {
 	return Variadic
 }
 
+// EachFile collects the markers of the given packages, and calls the callback for every
+// source file of the packages ordered by their paths, including the files which do not
+// have any marker. The markers of such files are empty. If there is any error, the callback
+// is called with the error first, and the files of the packages having errors are skipped.
 func EachFile(collector *Collector, pkgs []*Package, callback FileCallback) {
 	if collector == nil {
 		callback(nil, errors.New("collector cannot be nil"))
+		return
 	}
 
 	if pkgs == nil {
 		callback(nil, errors.New("pkgs(packages) cannot be nil"))
+		return
 	}
 
 	var fileMap = make(map[*ast.File]*File)
@@ -282,8 +446,11 @@ func EachFile(collector *Collector, pkgs []*Package, callback FileCallback) {
 	for _, pkg := range pkgs {
 		markers, err := collector.Collect(pkg)
 
-		if err != nil {
-			errs = append(errs, err.(ErrorList)...)
+		if errorList, ok := err.(ErrorList); ok {
+			errs = append(errs, errorList...)
+			continue
+		} else if err != nil {
+			errs = append(errs, err)
 			continue
 		}
 
@@ -298,11 +465,42 @@ func EachFile(collector *Collector, pkgs []*Package, callback FileCallback) {
 		callback(nil, NewErrorList(errs))
 	}
 
+	files := make([]*File, 0, len(fileMap))
+
 	for _, file := range fileMap {
+		files = append(files, file)
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].FullPath < files[j].FullPath
+	})
+
+	for _, file := range files {
 		callback(file, nil)
 	}
 }
 
+// EachFunctionWithMarker collects the markers of the given packages, and calls the callback
+// for every function carrying the marker with the given name, ordered by their file paths and
+// their positions. The methods are not included. If there is any error, the callback is called
+// with the error first, and the functions of the packages having errors are skipped.
+func EachFunctionWithMarker(collector *Collector, pkgs []*Package, markerName string, callback FunctionCallback) {
+	EachFile(collector, pkgs, func(file *File, err error) {
+		if err != nil {
+			callback(nil, err)
+			return
+		}
+
+		for index := range file.FunctionTypes {
+			function := &file.FunctionTypes[index]
+
+			if len(function.Markers[markerName]) != 0 {
+				callback(function, nil)
+			}
+		}
+	})
+}
+
 func eachPackage(pkg *Package, markers map[ast.Node]MarkerValues) map[*ast.File]*File {
 	var fileNodeMap = make(map[*ast.File]*File)
 	var methods = make([]Method, 0)
@@ -480,12 +678,9 @@ func getFileImports(fileSet *token.FileSet, file *ast.File) []Import {
 		}
 
 		imports = append(imports, Import{
-			Name: importName,
-			Path: importInfo.Path.Value[1 : len(importInfo.Path.Value)-1],
-			Position: Position{
-				importPosition.Line,
-				importPosition.Column,
-			},
+			Name:          importName,
+			Path:          importInfo.Path.Value[1 : len(importInfo.Path.Value)-1],
+			Position:      toPosition(importPosition),
 			RawImportSpec: importInfo,
 		})
 	}
@@ -789,10 +984,14 @@ func getMethod(fileSet *token.FileSet,
 }
 
 func getPosition(tokenFileSet *token.FileSet, pos token.Pos) Position {
-	position := tokenFileSet.Position(pos)
+	return toPosition(tokenFileSet.Position(pos))
+}
+
+func toPosition(position token.Position) Position {
 	return Position{
-		Line:   position.Line,
-		Column: position.Column,
+		file:   position.Filename,
+		line:   position.Line,
+		column: position.Column,
 	}
 }
 
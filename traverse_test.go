@@ -0,0 +1,189 @@
+package marker
+
+import (
+	"github.com/stretchr/testify/assert"
+	"go/ast"
+	"testing"
+)
+
+func TestField_TagAndDoc(t *testing.T) {
+	pkgs, err := LoadPackages("./test/package2")
+	assert.Nil(t, err)
+
+	var files []*File
+
+	EachFile(NewCollector(NewRegistry()), pkgs, func(file *File, err error) {
+		assert.Nil(t, err)
+		files = append(files, file)
+	})
+
+	assert.Len(t, files, 1)
+	assert.Len(t, files[0].StructTypes, 1)
+
+	fields := files[0].StructTypes[0].Fields
+	assert.Len(t, fields, 2)
+
+	assert.Equal(t, "title", fields[0].Tag().Get("json"))
+	assert.Equal(t, "+marker:field-level:Name=title", fields[0].Tag().Get("marker"))
+	assert.Equal(t, "Title is the title of the book.\nIt cannot be empty.\n", fields[0].Doc())
+
+	assert.Equal(t, "author", fields[1].Tag().Get("json"))
+	assert.Equal(t, "", fields[1].Doc())
+}
+
+func TestNode(t *testing.T) {
+	pkgs, err := LoadPackages("./test/package2")
+	assert.Nil(t, err)
+
+	EachFile(NewCollector(NewRegistry()), pkgs, func(file *File, err error) {
+		assert.Nil(t, err)
+
+		structType := file.StructTypes[0]
+		typeSpec, ok := structType.Node().(*ast.TypeSpec)
+		assert.True(t, ok)
+		assert.Equal(t, "Book", typeSpec.Name.Name)
+
+		field, ok := structType.Fields[0].Node().(*ast.Field)
+		assert.True(t, ok)
+		assert.Equal(t, "Title", field.Names[0].Name)
+
+		function, ok := file.FunctionTypes[0].Node().(*ast.FuncDecl)
+		assert.True(t, ok)
+		assert.Equal(t, "RegisterHandlers", function.Name.Name)
+	})
+
+	assert.Nil(t, StructType{}.Node())
+	assert.Nil(t, Method{}.Node())
+	assert.Nil(t, Field{}.Node())
+}
+
+func TestPosition(t *testing.T) {
+	pkgs, err := LoadPackages("./test/package2")
+	assert.Nil(t, err)
+
+	EachFile(NewCollector(NewRegistry()), pkgs, func(file *File, err error) {
+		assert.Nil(t, err)
+
+		position := file.StructTypes[0].Position
+		assert.Equal(t, file.FullPath, position.File())
+		assert.Equal(t, 3, position.Line())
+		assert.Equal(t, 6, position.Column())
+		assert.Equal(t, file.FullPath+":3:6", position.String())
+	})
+
+	assert.Equal(t, "-", Position{}.String())
+	assert.Equal(t, "3:5", Position{line: 3, column: 5}.String())
+	assert.Equal(t, "file.go", Position{file: "file.go"}.String())
+}
+
+type testMockReturnsMarker struct {
+	Value string `marker:"Value,useValueSyntax"`
+}
+
+func TestInterfaceType_MethodMarkers(t *testing.T) {
+	registry := NewRegistry()
+	err := registry.Register("mock:returns", "", InterfaceMethodLevel, &testMockReturnsMarker{})
+	assert.Nil(t, err)
+
+	pkg := parseTestPackage(t, map[string]string{
+		"repository.go": `package test
+
+type Book struct {
+	Title string
+}
+
+// BookRepository stores the books.
+type BookRepository interface {
+	// FindByTitle finds the book which has the given title.
+	// +mock:returns=nil
+	FindByTitle(title string) (*Book, error)
+	Count() int
+}
+`,
+	})
+
+	EachFile(NewCollector(registry), []*Package{pkg}, func(file *File, err error) {
+		assert.Nil(t, err)
+		assert.Len(t, file.InterfaceTypes, 1)
+
+		interfaceType := file.InterfaceTypes[0]
+		assert.Equal(t, "BookRepository", interfaceType.Name)
+		assert.Len(t, interfaceType.Methods, 2)
+
+		method := interfaceType.Methods[0]
+		assert.Equal(t, "FindByTitle", method.Name)
+		assert.Equal(t, testMockReturnsMarker{Value: "nil"}, method.Markers.Get("mock:returns"))
+
+		assert.Len(t, method.Parameters, 1)
+		assert.Equal(t, "title", method.Parameters[0].Name)
+		assert.Equal(t, Object, method.Parameters[0].Type.Kind())
+
+		assert.Len(t, method.ReturnValues, 2)
+		assert.Equal(t, Ptr, method.ReturnValues[0].Type.Kind())
+		assert.Equal(t, Object, method.ReturnValues[1].Type.Kind())
+
+		assert.Equal(t, "Count", interfaceType.Methods[1].Name)
+		assert.Nil(t, interfaceType.Methods[1].Markers)
+	})
+}
+
+func TestEachFile_FilesWithoutMarkers(t *testing.T) {
+	pkgs, err := LoadPackages("./test/testdata/package3")
+	assert.Nil(t, err)
+
+	registry := NewRegistry()
+	err = registry.Register("marker:handler", "", FunctionLiteralLevel, &testHandlerMarker{})
+	assert.Nil(t, err)
+	err = registry.Register("marker:package-level", "", PackageLevel, &testPackageLevelMarker{})
+	assert.Nil(t, err)
+
+	var fileNames []string
+	var markerCounts []int
+
+	EachFile(NewCollector(registry), pkgs, func(file *File, err error) {
+		assert.Nil(t, err)
+		fileNames = append(fileNames, file.Name)
+		markerCounts = append(markerCounts, len(file.Markers))
+	})
+
+	assert.Equal(t, []string{"handler.go", "order.go", "util.go"}, fileNames)
+	assert.Equal(t, []int{0, 1, 0}, markerCounts)
+}
+
+type testJobMarker struct {
+	Schedule string `marker:"schedule"`
+}
+
+func TestEachFunctionWithMarker(t *testing.T) {
+	registry := NewRegistry()
+	err := registry.Register("job", "", FunctionLevel, &testJobMarker{})
+	assert.Nil(t, err)
+
+	pkg := parseTestPackage(t, map[string]string{
+		"jobs.go": `package test
+
+// +job:schedule="@daily"
+func CleanUp() {
+}
+
+func helper() {
+}
+
+// +job:schedule="@hourly"
+func Sync() {
+}
+`,
+	})
+
+	var names []string
+	var schedules []string
+
+	EachFunctionWithMarker(NewCollector(registry), []*Package{pkg}, "job", func(function *FunctionType, err error) {
+		assert.Nil(t, err)
+		names = append(names, function.Name)
+		schedules = append(schedules, function.Markers.Get("job").(testJobMarker).Schedule)
+	})
+
+	assert.Equal(t, []string{"CleanUp", "Sync"}, names)
+	assert.Equal(t, []string{"@daily", "@hourly"}, schedules)
+}
@@ -1,10 +1,22 @@
 package marker
 
 import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"go/constant"
+	"go/token"
+	"math"
+	"net"
+	"net/url"
 	"reflect"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
+	"unicode"
 )
 
 type ArgumentType int
@@ -22,46 +34,143 @@ const (
 	StringType
 	SliceType
 	MapType
+	ImplementationType
+	DurationType
+	NestedStructType
+	CustomType
+	FloatType
+	RawJSONType
 )
 
 var argumentTypeText = map[ArgumentType]string{
-	InvalidType: "InvalidType",
-	RawType:     "RawType",
-	AnyType:     "AnyType",
-	BoolType:    "BoolType",
-	IntegerType: "IntegerType",
-	StringType:  "StringType",
-	SliceType:   "SliceType",
-	MapType:     "MapType",
+	InvalidType:        "InvalidType",
+	RawType:            "RawType",
+	AnyType:            "AnyType",
+	BoolType:           "BoolType",
+	IntegerType:        "IntegerType",
+	StringType:         "StringType",
+	SliceType:          "SliceType",
+	MapType:            "MapType",
+	ImplementationType: "ImplementationType",
+	DurationType:       "DurationType",
+	NestedStructType:   "NestedStructType",
+	CustomType:         "CustomType",
+	FloatType:          "FloatType",
+	RawJSONType:        "RawJSONType",
 }
 
 var (
 	interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
 	rawType       = reflect.TypeOf((*[]byte)(nil)).Elem()
+	durationType  = reflect.TypeOf(time.Duration(0))
+	restType      = reflect.TypeOf(map[string]interface{}{})
+	rawJSONType   = reflect.TypeOf(json.RawMessage{})
 )
 
+// builtinParsers are the parsers of the standard library types which are supported
+// without registering any parser. The parsers registered for the same types override them.
+var builtinParsers = map[reflect.Type]ArgumentParser{
+	reflect.TypeOf(net.IP{}):    parseIP,
+	reflect.TypeOf(url.URL{}):   parseURL,
+	reflect.TypeOf(time.Time{}): parseTime,
+}
+
+// parseIP parses an IPv4 or IPv6 address such as '192.168.1.1' or '::1'.
+func parseIP(text string) (interface{}, error) {
+	ip := net.ParseIP(text)
+
+	if ip == nil {
+		return nil, fmt.Errorf("%q is not a valid IP address", text)
+	}
+
+	return ip, nil
+}
+
+// parseURL parses a URL such as 'https://example.com/books'.
+func parseURL(text string) (interface{}, error) {
+	parsedURL, err := url.Parse(text)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return *parsedURL, nil
+}
+
+// parseTime parses a time in RFC 3339 format such as '2006-01-02T15:04:05Z'.
+func parseTime(text string) (interface{}, error) {
+	return time.Parse(time.RFC3339, text)
+}
+
+// ArgumentParser parses the text of an argument value into a value of the type
+// which it is registered for.
+type ArgumentParser func(text string) (interface{}, error)
+
 type ArgumentTypeInfo struct {
 	ActualType ArgumentType
 	ItemType   *ArgumentTypeInfo
+	// Type is the type of the argument, it is not a pointer type.
+	Type reflect.Type
+	// Parser is the registered or the built-in parser of the CustomType.
+	Parser ArgumentParser
+	// Separator is the element separator of the SliceType written without curly brackets
+	// such as '|' for 'a|b|c'. If it is zero, the elements are separated by semicolons.
+	Separator rune
+	// ExtendedBoolean makes the BoolType accept yes, on, no and off in addition to true and false.
+	ExtendedBoolean bool
+	// Interface is the interface type of the ImplementationType.
+	Interface reflect.Type
+	// Implementation is the concrete type registered for the Interface.
+	Implementation reflect.Type
+	// ImplementationInfo is the type info of the Implementation.
+	ImplementationInfo *ArgumentTypeInfo
+	// Fields are the arguments of the NestedStructType.
+	Fields map[string]Argument
+	// FieldNames are the struct field names of the arguments of the NestedStructType.
+	FieldNames map[string]string
 }
 
 func GetArgumentTypeInfo(typ reflect.Type) (ArgumentTypeInfo, error) {
+	return getArgumentTypeInfo(typ, nil)
+}
+
+// getArgumentTypeInfo returns the argument type info of the given type, the visiting
+// types are the nested struct types being extracted to detect recursive types.
+func getArgumentTypeInfo(typ reflect.Type, visiting map[reflect.Type]bool) (ArgumentTypeInfo, error) {
 	typeInfo := &ArgumentTypeInfo{}
 
 	if typ.Kind() == reflect.Ptr {
 		typ = typ.Elem()
 	}
 
+	typeInfo.Type = typ
+
 	if typ == rawType {
 		typeInfo.ActualType = RawType
 		return *typeInfo, nil
 	}
 
+	if typ == rawJSONType {
+		typeInfo.ActualType = RawJSONType
+		return *typeInfo, nil
+	}
+
 	if typ == interfaceType {
 		typeInfo.ActualType = AnyType
 		return *typeInfo, nil
 	}
 
+	if typ == durationType {
+		typeInfo.ActualType = DurationType
+		return *typeInfo, nil
+	}
+
+	if parser, ok := builtinParsers[typ]; ok {
+		typeInfo.ActualType = CustomType
+		typeInfo.Parser = parser
+		return *typeInfo, nil
+	}
+
 	switch typ.Kind() {
 	case reflect.String:
 		typeInfo.ActualType = StringType
@@ -69,11 +178,13 @@ func GetArgumentTypeInfo(typ reflect.Type) (ArgumentTypeInfo, error) {
 		typeInfo.ActualType = IntegerType
 	case reflect.Int8, reflect.Int16, reflect.Int, reflect.Int32, reflect.Int64:
 		typeInfo.ActualType = IntegerType
+	case reflect.Float32, reflect.Float64:
+		typeInfo.ActualType = FloatType
 	case reflect.Bool:
 		typeInfo.ActualType = BoolType
 	case reflect.Slice:
 		typeInfo.ActualType = SliceType
-		itemType, err := GetArgumentTypeInfo(typ.Elem())
+		itemType, err := getArgumentTypeInfo(typ.Elem(), visiting)
 
 		if err != nil {
 			return ArgumentTypeInfo{}, fmt.Errorf("bad slice item type: %w", err)
@@ -86,13 +197,24 @@ func GetArgumentTypeInfo(typ reflect.Type) (ArgumentTypeInfo, error) {
 		}
 
 		typeInfo.ActualType = MapType
-		itemType, err := GetArgumentTypeInfo(typ.Elem())
+		itemType, err := getArgumentTypeInfo(typ.Elem(), visiting)
 
 		if err != nil {
 			return ArgumentTypeInfo{}, fmt.Errorf("bad map item type: %w", err)
 		}
 
 		typeInfo.ItemType = &itemType
+	case reflect.Interface:
+		// the concrete type is resolved by the registered implementations
+		typeInfo.ActualType = ImplementationType
+		typeInfo.Interface = typ
+	case reflect.Struct:
+		typeInfo.ActualType = NestedStructType
+		err := typeInfo.extractNestedFields(typ, visiting)
+
+		if err != nil {
+			return ArgumentTypeInfo{}, err
+		}
 	default:
 		return ArgumentTypeInfo{}, fmt.Errorf("type has unsupported kind %s", typ.Kind())
 	}
@@ -106,12 +228,26 @@ func (typeInfo ArgumentTypeInfo) Parse(scanner *Scanner, out reflect.Value) erro
 		return typeInfo.parseBoolean(scanner, out)
 	case IntegerType:
 		return typeInfo.parseInteger(scanner, out)
+	case FloatType:
+		return typeInfo.parseFloat(scanner, out)
 	case StringType:
 		return typeInfo.parseString(scanner, out)
+	case DurationType:
+		return typeInfo.parseDuration(scanner, out)
+	case RawType:
+		return typeInfo.parseRaw(scanner, out)
+	case RawJSONType:
+		return typeInfo.parseRawJSON(scanner, out)
 	case SliceType:
 		return typeInfo.parseSlice(scanner, out)
 	case MapType:
 		return typeInfo.parseMap(scanner, out)
+	case ImplementationType:
+		return typeInfo.parseImplementation(scanner, out)
+	case NestedStructType:
+		return typeInfo.parseNestedStruct(scanner, out)
+	case CustomType:
+		return typeInfo.parseCustomType(scanner, out)
 	case AnyType:
 		inferredType, _ := typeInfo.inferType(scanner, out, false)
 		newOut := out
@@ -160,6 +296,11 @@ func (typeInfo ArgumentTypeInfo) setValue(out, value reflect.Value) {
 
 	if outType.Kind() == reflect.Ptr {
 		outType = outType.Elem()
+
+		if out.IsNil() {
+			out.Set(reflect.New(outType))
+		}
+
 		out = out.Elem()
 	}
 
@@ -182,11 +323,26 @@ func (typeInfo ArgumentTypeInfo) parseBoolean(scanner *Scanner, out reflect.Valu
 	switch scanner.Token() {
 	case "false":
 		typeInfo.setValue(out, reflect.ValueOf(false))
+		return nil
 	case "true":
 		typeInfo.setValue(out, reflect.ValueOf(true))
+		return nil
+	}
+
+	if !typeInfo.ExtendedBoolean {
+		return fmt.Errorf("expected true or false, got %q", scanner.Token())
+	}
+
+	switch scanner.Token() {
+	case "no", "off":
+		typeInfo.setValue(out, reflect.ValueOf(false))
+		return nil
+	case "yes", "on":
+		typeInfo.setValue(out, reflect.ValueOf(true))
+		return nil
 	}
 
-	return fmt.Errorf("expected true or false, got %q", scanner.Token())
+	return fmt.Errorf("expected true, false, yes, no, on or off, got %q", scanner.Token())
 }
 
 func (typeInfo ArgumentTypeInfo) parseInteger(scanner *Scanner, out reflect.Value) error {
@@ -203,17 +359,40 @@ func (typeInfo ArgumentTypeInfo) parseInteger(scanner *Scanner, out reflect.Valu
 		scanner.Scan()
 	}
 
+	// the name of a constant such as 'MaxSize' is resolved to its value
+	if scanner.resolveConstant != nil && IsIdentifier(scanner.SkipWhitespaces(), 0) {
+		return typeInfo.parseIntegerConstant(scanner, out, isNegative)
+	}
+
 	if !scanner.Expect(Integer, "Integer") {
 		return nil
 	}
 
 	text := scanner.Token()
 
+	if strings.HasSuffix(text, "_") || strings.Contains(text, "__") {
+		return fmt.Errorf("unable to parse integer: '_' must separate successive digits in %q", text)
+	}
+
 	if isNegative {
 		text = "-" + text
 	}
 
-	intValue, err := strconv.Atoi(text)
+	// the fraction of a float such as '10.5' is not truncated silently
+	if scanner.Peek() == '.' {
+		character := scanner.Next()
+		fraction := "."
+
+		for IsDecimal(character) || character == '_' {
+			fraction += string(character)
+			character = scanner.Next()
+		}
+
+		scanner.character = character
+		return fmt.Errorf("expected integer, got float %s%s", text, fraction)
+	}
+
+	intValue, err := strconv.Atoi(strings.ReplaceAll(text, "_", ""))
 
 	typeInfo.setValue(out, reflect.ValueOf(intValue))
 
@@ -224,17 +403,33 @@ func (typeInfo ArgumentTypeInfo) parseInteger(scanner *Scanner, out reflect.Valu
 	return nil
 }
 
+// parseString parses a quoted or an unquoted string. The content of a quoted string
+// is kept verbatim including its leading and trailing whitespaces, whereas an unquoted
+// string is trimmed. A raw string in backticks spanning multiple comment lines keeps
+// its newlines in the parsed value, the comment characters of the lines are not part
+// of it. The newlines can be written in double quotes as '\n' as well.
 func (typeInfo ArgumentTypeInfo) parseString(scanner *Scanner, out reflect.Value) error {
 	if scanner == nil {
 		return errors.New("scanner cannot be nil")
 	}
 
 	startPosition := scanner.searchIndex
+	errorCount := scanner.ErrorCount()
+
+	// the search index is -1 if nothing has been scanned yet
+	if startPosition < 0 {
+		startPosition = 0
+	}
 
 	token := scanner.Scan()
 
 	if token == String {
 
+		// the error has already been reported by the scanner
+		if scanner.ErrorCount() > errorCount {
+			return nil
+		}
+
 		value, err := strconv.Unquote(scanner.Token())
 
 		if err != nil {
@@ -245,18 +440,247 @@ func (typeInfo ArgumentTypeInfo) parseString(scanner *Scanner, out reflect.Value
 		return nil
 	}
 
-	for character := scanner.SkipWhitespaces(); character != ',' && character != ';' && character != ':' && character != '}' && character != EOF; character = scanner.SkipWhitespaces() {
+	for character := scanner.SkipWhitespaces(); !scanner.IsTerminator(character); character = scanner.SkipWhitespaces() {
 		scanner.Scan()
 	}
 
 	endPosition := scanner.searchIndex
 
-	value := string(scanner.source[startPosition:endPosition])
+	// the surrounding whitespaces such as the carriage return of CRLF line endings
+	// are not part of the value
+	value := strings.TrimSpace(string(scanner.source[startPosition:endPosition]))
+
+	// the newlines of the joined comment lines are skipped by the scanner, the other
+	// control characters can only be written in quoted values by using escapes
+	for _, character := range value {
+		if unicode.IsControl(character) && character != '\n' {
+			return fmt.Errorf("unquoted value %q cannot contain control character %U, use a quoted value instead", value, character)
+		}
+	}
+
 	typeInfo.setValue(out, reflect.ValueOf(value))
 
 	return nil
 }
 
+// parseIntegerConstant parses the name of a Go constant, and sets the field to its value.
+func (typeInfo ArgumentTypeInfo) parseIntegerConstant(scanner *Scanner, out reflect.Value, isNegative bool) error {
+	name := scanner.scanConstantName()
+	value, ok := scanner.resolveConstant(name)
+
+	if !ok {
+		return fmt.Errorf("unable to resolve constant %q", name)
+	}
+
+	if value = constant.ToInt(value); value.Kind() != constant.Int {
+		return fmt.Errorf("constant %q is not an integer", name)
+	}
+
+	if isNegative {
+		value = constant.UnaryOp(token.SUB, value, 0)
+	}
+
+	intValue, exact := constant.Int64Val(value)
+
+	if !exact || int64(int(intValue)) != intValue {
+		return fmt.Errorf("argument value of constant %q is out of range: %w", name, strconv.ErrRange)
+	}
+
+	typeInfo.setValue(out, reflect.ValueOf(int(intValue)))
+	return nil
+}
+
+// parseFloat parses a floating-point number such as -0.0, 1.5e308 or 1e-320. A value
+// whose magnitude is too large for the type is not rounded to infinity, an error
+// wrapping strconv.ErrRange is returned instead.
+func (typeInfo ArgumentTypeInfo) parseFloat(scanner *Scanner, out reflect.Value) error {
+	if scanner == nil {
+		return errors.New("scanner cannot be nil")
+	}
+
+	// the exponent and the fraction are not single tokens, they are read in the same way as strings
+	var text string
+	err := (ArgumentTypeInfo{ActualType: StringType}).parseString(scanner, reflect.ValueOf(&text).Elem())
+
+	if err != nil {
+		return err
+	}
+
+	bitSize := 64
+
+	if typeInfo.Type != nil && typeInfo.Type.Kind() == reflect.Float32 {
+		bitSize = 32
+	}
+
+	floatValue, err := strconv.ParseFloat(text, bitSize)
+	isFinite := err != nil || !math.IsInf(floatValue, 0) && !math.IsNaN(floatValue)
+
+	// the name of a constant such as 'Pi' or 'math.Pi' is resolved to its value
+	if name := strings.TrimPrefix(text, "-"); (err != nil || !isFinite) && scanner.resolveConstant != nil && name != "" && IsIdentifier(rune(name[0]), 0) {
+		return typeInfo.parseFloatConstant(scanner, out, text, bitSize)
+	}
+
+	if err != nil {
+		if errors.Is(err, strconv.ErrRange) {
+			return fmt.Errorf("unable to parse float: %q is out of range: %w", text, strconv.ErrRange)
+		}
+
+		return fmt.Errorf("unable to parse float: %q is not a valid float", text)
+	}
+
+	// the non-finite values such as 'inf' and 'NaN' accepted by strconv are not allowed
+	if !isFinite {
+		return fmt.Errorf("unable to parse float: %q is not a finite number", text)
+	}
+
+	if bitSize == 32 {
+		typeInfo.setValue(out, reflect.ValueOf(float32(floatValue)))
+	} else {
+		typeInfo.setValue(out, reflect.ValueOf(floatValue))
+	}
+
+	return nil
+}
+
+// parseFloatConstant sets the field to the value of the Go constant with the given name.
+func (typeInfo ArgumentTypeInfo) parseFloatConstant(scanner *Scanner, out reflect.Value, name string, bitSize int) error {
+	value, ok := scanner.resolveConstant(strings.TrimPrefix(name, "-"))
+
+	if !ok {
+		return fmt.Errorf("unable to resolve constant %q", strings.TrimPrefix(name, "-"))
+	}
+
+	if value = constant.ToFloat(value); value.Kind() != constant.Float && value.Kind() != constant.Int {
+		return fmt.Errorf("constant %q is not a number", strings.TrimPrefix(name, "-"))
+	}
+
+	floatValue, _ := constant.Float64Val(value)
+
+	if strings.HasPrefix(name, "-") {
+		floatValue = -floatValue
+	}
+
+	if bitSize == 32 {
+		typeInfo.setValue(out, reflect.ValueOf(float32(floatValue)))
+	} else {
+		typeInfo.setValue(out, reflect.ValueOf(floatValue))
+	}
+
+	return nil
+}
+
+func (typeInfo ArgumentTypeInfo) parseDuration(scanner *Scanner, out reflect.Value) error {
+	if scanner == nil {
+		return errors.New("scanner cannot be nil")
+	}
+
+	// durations such as 1h30m or "1.5s" are parsed in the same way as strings
+	var text string
+	err := (ArgumentTypeInfo{ActualType: StringType}).parseString(scanner, reflect.ValueOf(&text).Elem())
+
+	if err != nil {
+		return err
+	}
+
+	duration, err := time.ParseDuration(text)
+
+	if err != nil {
+		return fmt.Errorf("unable to parse duration: %v", err)
+	}
+
+	typeInfo.setValue(out, reflect.ValueOf(duration))
+	return nil
+}
+
+// parseRaw parses the bytes of a []byte argument. The value is decoded if it has the 'hex:'
+// or the 'base64:' prefix such as "hex:deadbeef", otherwise the bytes of the text are used.
+func (typeInfo ArgumentTypeInfo) parseRaw(scanner *Scanner, out reflect.Value) error {
+	if scanner == nil {
+		return errors.New("scanner cannot be nil")
+	}
+
+	var text string
+	err := (ArgumentTypeInfo{ActualType: StringType}).parseString(scanner, reflect.ValueOf(&text).Elem())
+
+	if err != nil {
+		return err
+	}
+
+	bytes := []byte(text)
+
+	if strings.HasPrefix(text, "hex:") {
+		bytes, err = hex.DecodeString(strings.TrimPrefix(text, "hex:"))
+
+		if err != nil {
+			return fmt.Errorf("unable to decode hex: %v", err)
+		}
+	} else if strings.HasPrefix(text, "base64:") {
+		bytes, err = base64.StdEncoding.DecodeString(strings.TrimPrefix(text, "base64:"))
+
+		if err != nil {
+			return fmt.Errorf("unable to decode base64: %v", err)
+		}
+	}
+
+	typeInfo.setValue(out, reflect.ValueOf(bytes))
+	return nil
+}
+
+// parseRawJSON captures the text of a value enclosed in balanced braces or brackets such as
+// '{"name":"test","tags":["a"]}' as it is, without interpreting it. The braces and brackets
+// in the quoted strings are not counted.
+func (typeInfo ArgumentTypeInfo) parseRawJSON(scanner *Scanner, out reflect.Value) error {
+	if scanner == nil {
+		return errors.New("scanner cannot be nil")
+	}
+
+	character := scanner.SkipWhitespaces()
+
+	if character != '{' && character != '[' {
+		return fmt.Errorf("expected '{' or '[' for raw JSON value, got %q", character)
+	}
+
+	startPosition := scanner.searchIndex
+	closers := make([]rune, 0)
+	inString := false
+
+	for {
+		switch {
+		case character == EOF:
+			scanner.character = character
+			return fmt.Errorf("unterminated raw JSON value, '%c' is missing", closers[len(closers)-1])
+		case inString && character == '\\':
+			scanner.Next()
+		case character == '"':
+			inString = !inString
+		case inString:
+		case character == '{':
+			closers = append(closers, '}')
+		case character == '[':
+			closers = append(closers, ']')
+		case character == '}' || character == ']':
+			if character != closers[len(closers)-1] {
+				scanner.character = character
+				return fmt.Errorf("unbalanced raw JSON value, got '%c' but '%c' is expected", character, closers[len(closers)-1])
+			}
+
+			closers = closers[:len(closers)-1]
+		}
+
+		if len(closers) == 0 {
+			break
+		}
+
+		character = scanner.Next()
+	}
+
+	scanner.character = scanner.Next()
+	text := append([]byte{}, scanner.source[startPosition:scanner.searchIndex]...)
+
+	typeInfo.setValue(out, reflect.ValueOf(json.RawMessage(text)))
+	return nil
+}
+
 func (typeInfo ArgumentTypeInfo) parseSlice(scanner *Scanner, out reflect.Value) error {
 	if scanner == nil {
 		return errors.New("scanner cannot be nil")
@@ -265,10 +689,22 @@ func (typeInfo ArgumentTypeInfo) parseSlice(scanner *Scanner, out reflect.Value)
 	sliceType := reflect.Zero(out.Type())
 	sliceItemType := reflect.Indirect(reflect.New(out.Type().Elem()))
 
+	// the elements in braces such as '{a,b}' are separated by commas, and ';' is a part of
+	// the unquoted strings such as '{a;b}'. The bare elements such as 'a;b' are separated
+	// by semicolons, and the comma ends the argument.
 	if scanner.SkipWhitespaces() == '{' {
 
 		scanner.Scan()
 
+		if typeInfo.ItemType.ActualType == StringType {
+			terminators := scanner.Terminators
+			scanner.Terminators = removeTerminator(terminators, ';')
+
+			defer func() {
+				scanner.Terminators = terminators
+			}()
+		}
+
 		for character := scanner.SkipWhitespaces(); character != '}' && character != EOF; character = scanner.SkipWhitespaces() {
 			err := typeInfo.ItemType.Parse(scanner, sliceItemType)
 
@@ -297,7 +733,16 @@ func (typeInfo ArgumentTypeInfo) parseSlice(scanner *Scanner, out reflect.Value)
 		return nil
 	}
 
-	for character := scanner.SkipWhitespaces(); character != ',' && character != '}' && character != EOF; character = scanner.SkipWhitespaces() {
+	if typeInfo.Separator != 0 {
+		return typeInfo.parseSeparatedSlice(scanner, out)
+	}
+
+	// the bare elements end at the terminators of the scanner except the semicolon separating them
+	isSliceEnd := func(character rune) bool {
+		return character != ';' && scanner.IsTerminator(character)
+	}
+
+	for character := scanner.SkipWhitespaces(); !isSliceEnd(character); character = scanner.SkipWhitespaces() {
 		err := typeInfo.ItemType.Parse(scanner, sliceItemType)
 
 		if err != nil {
@@ -306,17 +751,81 @@ func (typeInfo ArgumentTypeInfo) parseSlice(scanner *Scanner, out reflect.Value)
 
 		sliceType = reflect.Append(sliceType, sliceItemType)
 
-		token := scanner.SkipWhitespaces()
-
-		if token == ',' || token == '}' || token == EOF {
+		if isSliceEnd(scanner.SkipWhitespaces()) {
 			break
 		}
 
-		scanner.Scan()
+		if !scanner.Expect(';', "Semicolon ';'") {
+			return nil
+		}
+	}
+
+	typeInfo.setValue(out, sliceType)
+	return nil
+}
 
-		if token != ';' {
+// removeTerminator returns the given terminators without the given terminator,
+// the default terminators are used if the terminators are nil.
+func removeTerminator(terminators []rune, terminator rune) []rune {
+	if terminators == nil {
+		terminators = defaultTerminators
+	}
+
+	result := make([]rune, 0, len(terminators))
+
+	for _, current := range terminators {
+		if current != terminator {
+			result = append(result, current)
+		}
+	}
+
+	return result
+}
+
+// parseSeparatedSlice parses the elements separated by the configured separator such as
+// 'a|b|c'. The whole value is read in the same way as an unquoted string, and then each
+// element is parsed separately, so the separator cannot be used inside the elements.
+func (typeInfo ArgumentTypeInfo) parseSeparatedSlice(scanner *Scanner, out reflect.Value) error {
+	var text string
+	err := (ArgumentTypeInfo{ActualType: StringType}).parseString(scanner, reflect.ValueOf(&text).Elem())
+
+	if err != nil {
+		return err
+	}
+
+	var elements []string
+
+	if typeInfo.Separator == ' ' {
+		elements = strings.Fields(text)
+	} else if text != "" {
+		elements = strings.Split(text, string(typeInfo.Separator))
+	}
+
+	sliceType := reflect.MakeSlice(out.Type(), 0, len(elements))
+	sliceItemType := reflect.Indirect(reflect.New(out.Type().Elem()))
+
+	for _, element := range elements {
+		itemScanner := NewScanner(strings.TrimSpace(element))
+		itemScanner.resolveConstant = scanner.resolveConstant
+		itemScanner.ErrorCallback = func(itemScanner *Scanner, message string) {
+			scanner.AddError(message)
+		}
+
+		err = typeInfo.ItemType.Parse(itemScanner, sliceItemType)
+
+		if err != nil {
+			return err
+		}
+
+		if itemScanner.ErrorCount() != 0 {
 			return nil
 		}
+
+		if itemScanner.SkipWhitespaces() != EOF {
+			return fmt.Errorf("unexpected %q in slice element %q", string(itemScanner.source[itemScanner.searchIndex:]), element)
+		}
+
+		sliceType = reflect.Append(sliceType, sliceItemType)
 	}
 
 	typeInfo.setValue(out, sliceType)
@@ -328,15 +837,20 @@ func (typeInfo ArgumentTypeInfo) parseMap(scanner *Scanner, out reflect.Value) e
 		return errors.New("scanner cannot be nil")
 	}
 
+	if character := scanner.SkipWhitespaces(); character != '{' && !scanner.IsTerminator(character) {
+		return typeInfo.parseBracelessMap(scanner, out)
+	}
+
 	mapType := reflect.MakeMap(out.Type())
-	key := reflect.Indirect(reflect.New(out.Type().Key()))
-	value := reflect.Indirect(reflect.New(out.Type().Elem()))
 
 	if !scanner.Expect('{', "Left Curly Bracket") {
 		return nil
 	}
 
 	for character := scanner.SkipWhitespaces(); character != '}' && character != EOF; character = scanner.SkipWhitespaces() {
+		// a new key and value are allocated for each entry so that the entries do not share them
+		key := reflect.Indirect(reflect.New(out.Type().Key()))
+		value := reflect.Indirect(reflect.New(out.Type().Elem()))
 		err := typeInfo.parseString(scanner, key)
 
 		if err != nil {
@@ -373,6 +887,291 @@ func (typeInfo ArgumentTypeInfo) parseMap(scanner *Scanner, out reflect.Value) e
 	return nil
 }
 
+// parseBracelessMap parses the entries written without curly brackets such as 'a:1,b:2'.
+// The entries are separated by commas like the arguments, so a comma followed by an argument
+// name and an equals sign such as ',name=' ends the map and begins the next argument.
+func (typeInfo ArgumentTypeInfo) parseBracelessMap(scanner *Scanner, out reflect.Value) error {
+	mapType := reflect.MakeMap(out.Type())
+
+	for {
+		key := reflect.Indirect(reflect.New(out.Type().Key()))
+		value := reflect.Indirect(reflect.New(out.Type().Elem()))
+		err := typeInfo.parseString(scanner, key)
+
+		if err != nil {
+			return err
+		}
+
+		if !scanner.Expect(':', "Colon ':'") {
+			return nil
+		}
+
+		err = typeInfo.ItemType.Parse(scanner, value)
+
+		if err != nil {
+			return err
+		}
+
+		mapType.SetMapIndex(key, value)
+
+		if scanner.SkipWhitespaces() != ',' || !isNextMapEntry(scanner) {
+			break
+		}
+
+		scanner.Scan()
+	}
+
+	typeInfo.setValue(out, mapType)
+	return nil
+}
+
+// isNextMapEntry checks if the comma at the current position is followed by another map
+// entry such as ',b:2' rather than the next argument such as ',name=value'. The position
+// of the scanner is not changed.
+func isNextMapEntry(scanner *Scanner) bool {
+	searchIndex := scanner.searchIndex
+	defer scanner.SetSearchIndex(searchIndex)
+
+	scanner.Scan()
+
+	switch scanner.SkipWhitespaces() {
+	case '"', '\'', '`':
+		// the argument names are not quoted
+		return true
+	}
+
+	token := scanner.Scan()
+
+	for token != ':' && token != '=' && token != ',' && token != '}' && token != EOF {
+		token = scanner.Scan()
+	}
+
+	return token == ':'
+}
+
+// extractNestedFields extracts the arguments of the given nested struct type.
+func (typeInfo *ArgumentTypeInfo) extractNestedFields(typ reflect.Type, visiting map[reflect.Type]bool) error {
+	if visiting[typ] {
+		return fmt.Errorf("nested struct type %v cannot be recursive", typ)
+	}
+
+	if visiting == nil {
+		visiting = make(map[reflect.Type]bool)
+	}
+
+	visiting[typ] = true
+	defer delete(visiting, typ)
+
+	typeInfo.Fields = make(map[string]Argument)
+	typeInfo.FieldNames = make(map[string]string)
+
+	for index := 0; index < typ.NumField(); index++ {
+		field := typ.Field(index)
+
+		if field.PkgPath != "" {
+			continue
+		}
+
+		argument, err := extractArgument(field, visiting)
+
+		if err != nil {
+			return err
+		}
+
+		if argument.SyntaxFree || argument.UseValueSyntax {
+			return fmt.Errorf("'%s' field of nested struct type %v cannot have syntaxFree or useValueSyntax option", argument.Name, typ)
+		}
+
+		typeInfo.Fields[argument.Name] = argument
+		typeInfo.FieldNames[argument.Name] = field.Name
+	}
+
+	return nil
+}
+
+// parseNestedStruct parses the arguments of a nested struct written in curly brackets
+// such as '{name=a,level=1}'. The arguments are separated by commas in the same way
+// as the arguments of a marker.
+func (typeInfo ArgumentTypeInfo) parseNestedStruct(scanner *Scanner, out reflect.Value) error {
+	if scanner == nil {
+		return errors.New("scanner cannot be nil")
+	}
+
+	structType := out.Type()
+
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+
+	structValue := reflect.Indirect(reflect.New(structType))
+
+	if !scanner.Expect('{', "Left Curly Bracket '{'") {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+
+	for character := scanner.SkipWhitespaces(); character != '}' && character != EOF; character = scanner.SkipWhitespaces() {
+		if !scanner.Expect(Identifier, "Argument Name") {
+			return nil
+		}
+
+		argumentName := scanner.Token()
+
+		if !scanner.Expect('=', "Equals Sign '='") {
+			return nil
+		}
+
+		argument, exists := typeInfo.Fields[argumentName]
+
+		if exists {
+			err := argument.TypeInfo.Parse(scanner, structValue.FieldByName(typeInfo.FieldNames[argumentName]))
+
+			if err != nil {
+				return err
+			}
+
+			seen[argumentName] = true
+		} else {
+			// the value of an unknown argument is parsed to skip
+			var anyValue interface{}
+			(&ArgumentTypeInfo{ActualType: AnyType}).Parse(scanner, reflect.ValueOf(&anyValue))
+		}
+
+		if scanner.SkipWhitespaces() == '}' {
+			break
+		}
+
+		if !scanner.Expect(',', "Comma ','") {
+			return nil
+		}
+	}
+
+	if !scanner.Expect('}', "Right Curly Bracket '}'") {
+		return nil
+	}
+
+	missingArguments := make([]string, 0)
+
+	for argumentName, argument := range typeInfo.Fields {
+		if argument.Required && !seen[argumentName] {
+			missingArguments = append(missingArguments, argumentName)
+		}
+	}
+
+	if len(missingArguments) != 0 {
+		sort.Strings(missingArguments)
+		return fmt.Errorf("missing argument(s) %s of %v", strings.Join(missingArguments, ", "), structType)
+	}
+
+	typeInfo.setValue(out, structValue)
+	return nil
+}
+
+func (typeInfo ArgumentTypeInfo) parseImplementation(scanner *Scanner, out reflect.Value) error {
+	if scanner == nil {
+		return errors.New("scanner cannot be nil")
+	}
+
+	if typeInfo.Implementation == nil || typeInfo.ImplementationInfo == nil {
+		return fmt.Errorf("there is no registered implementation for the interface type %v", typeInfo.Interface)
+	}
+
+	implementation := reflect.Indirect(reflect.New(typeInfo.Implementation))
+	err := typeInfo.ImplementationInfo.Parse(scanner, implementation)
+
+	if err != nil {
+		return err
+	}
+
+	if out.Kind() == reflect.Ptr {
+		if out.IsNil() {
+			out.Set(reflect.New(out.Type().Elem()))
+		}
+
+		out = out.Elem()
+	}
+
+	out.Set(implementation)
+	return nil
+}
+
+// parseCustomType parses the text of a quoted or an unquoted value by using the registered or the built-in parser.
+func (typeInfo ArgumentTypeInfo) parseCustomType(scanner *Scanner, out reflect.Value) error {
+	if scanner == nil {
+		return errors.New("scanner cannot be nil")
+	}
+
+	var text string
+	err := (ArgumentTypeInfo{ActualType: StringType}).parseString(scanner, reflect.ValueOf(&text).Elem())
+
+	if err != nil {
+		return err
+	}
+
+	value, err := typeInfo.Parser(text)
+
+	if err != nil {
+		return fmt.Errorf("unable to parse %v: %v", typeInfo.Type, err)
+	}
+
+	parsedValue := reflect.ValueOf(value)
+
+	if !parsedValue.IsValid() || !parsedValue.Type().ConvertibleTo(typeInfo.Type) {
+		return fmt.Errorf("parser of %v returned a value of incompatible type %T", typeInfo.Type, value)
+	}
+
+	typeInfo.setValue(out, parsedValue)
+	return nil
+}
+
+// resolveType resolves the registered parsers of the types and the registered
+// concrete types of the interface types.
+func (typeInfo *ArgumentTypeInfo) resolveType(implementations map[reflect.Type]reflect.Type, parsers map[reflect.Type]ArgumentParser) error {
+	if parser, ok := parsers[typeInfo.Type]; ok {
+		*typeInfo = ArgumentTypeInfo{
+			ActualType: CustomType,
+			Type:       typeInfo.Type,
+			Parser:     parser,
+		}
+		return nil
+	}
+
+	if typeInfo.ItemType != nil {
+		return typeInfo.ItemType.resolveType(implementations, parsers)
+	}
+
+	for argumentName, argument := range typeInfo.Fields {
+		err := argument.TypeInfo.resolveType(implementations, parsers)
+
+		if err != nil {
+			return err
+		}
+
+		typeInfo.Fields[argumentName] = argument
+	}
+
+	if typeInfo.ActualType != ImplementationType {
+		return nil
+	}
+
+	implementation, ok := implementations[typeInfo.Interface]
+
+	if !ok {
+		return fmt.Errorf("there is no registered implementation for the interface type %v", typeInfo.Interface)
+	}
+
+	implementationInfo, err := GetArgumentTypeInfo(implementation)
+
+	if err != nil {
+		return err
+	}
+
+	typeInfo.Implementation = implementation
+	typeInfo.ImplementationInfo = &implementationInfo
+	return nil
+}
+
 func (typeInfo ArgumentTypeInfo) inferType(scanner *Scanner, out reflect.Value, ignoreLegacySlice bool) (ArgumentTypeInfo, error) {
 
 	character := scanner.SkipWhitespaces()
@@ -429,6 +1228,14 @@ func (typeInfo ArgumentTypeInfo) inferType(scanner *Scanner, out reflect.Value,
 			}
 		}
 
+		// the elements of different types such as {1, 2.5, true} are kept as interface{},
+		// but the elements are strings if the first element is a string such as {a, 1}
+		if elementType.ActualType != StringType && typeInfo.hasMixedElements(scanner, out, searchIndex+1, elementType) {
+			elementType = ArgumentTypeInfo{
+				ActualType: AnyType,
+			}
+		}
+
 		scanner.SetSearchIndex(searchIndex)
 
 		return ArgumentTypeInfo{
@@ -468,9 +1275,7 @@ func (typeInfo ArgumentTypeInfo) inferType(scanner *Scanner, out reflect.Value,
 		}
 
 		if token == Integer {
-			return ArgumentTypeInfo{
-				ActualType: IntegerType,
-			}, nil
+			return inferNumberType(scanner, searchIndex), nil
 		}
 
 	}
@@ -480,6 +1285,88 @@ func (typeInfo ArgumentTypeInfo) inferType(scanner *Scanner, out reflect.Value,
 	}, nil
 }
 
+// inferNumberType infers the type of the number starting at the given index such as 10,
+// -2.5 or 1e3. The integers are inferred as FloatType if the scanner prefers floats, and
+// the text which is not a valid number such as 1.2.3 is inferred as StringType.
+func inferNumberType(scanner *Scanner, searchIndex int) ArgumentTypeInfo {
+	character := scanner.Peek()
+
+	if character != '.' && character != 'e' && character != 'E' {
+		if scanner.PreferFloat {
+			return ArgumentTypeInfo{
+				ActualType: FloatType,
+			}
+		}
+
+		return ArgumentTypeInfo{
+			ActualType: IntegerType,
+		}
+	}
+
+	scanner.SetSearchIndex(searchIndex)
+
+	var text string
+	err := (ArgumentTypeInfo{ActualType: StringType}).parseString(scanner, reflect.ValueOf(&text).Elem())
+
+	if err == nil {
+		_, err = strconv.ParseFloat(text, 64)
+	}
+
+	if err != nil {
+		return ArgumentTypeInfo{
+			ActualType: StringType,
+		}
+	}
+
+	return ArgumentTypeInfo{
+		ActualType: FloatType,
+	}
+}
+
+// hasMixedElements checks if any element of the slice starting at the given index is
+// inferred as another type than the given type of the first element.
+func (typeInfo ArgumentTypeInfo) hasMixedElements(scanner *Scanner, out reflect.Value, searchIndex int, elementType ArgumentTypeInfo) bool {
+	scanner.SetSearchIndex(searchIndex)
+
+	for character := scanner.SkipWhitespaces(); character != '}' && character != EOF; character = scanner.SkipWhitespaces() {
+		elementIndex := scanner.searchIndex
+		itemType, _ := typeInfo.inferType(scanner, out, true)
+
+		if itemType.ActualType != elementType.ActualType {
+			return true
+		}
+
+		scanner.SetSearchIndex(elementIndex)
+
+		if !skipElement(scanner) {
+			break
+		}
+	}
+
+	return false
+}
+
+// skipElement skips the current element of a slice including its nested slices and maps,
+// and the comma after it. It returns false if there is no comma after the element.
+func skipElement(scanner *Scanner) bool {
+	depth := 0
+
+	for character := scanner.SkipWhitespaces(); character != EOF; character = scanner.SkipWhitespaces() {
+		if depth == 0 && (character == ',' || character == '}') {
+			return scanner.Scan() == ','
+		}
+
+		switch scanner.Scan() {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		}
+	}
+
+	return false
+}
+
 func (typeInfo ArgumentTypeInfo) makeSliceType() (reflect.Type, error) {
 	if typeInfo.ActualType != SliceType {
 		return nil, errors.New("this is not slice type")
@@ -493,10 +1380,14 @@ func (typeInfo ArgumentTypeInfo) makeSliceType() (reflect.Type, error) {
 	switch typeInfo.ItemType.ActualType {
 	case IntegerType:
 		itemType = reflect.TypeOf(int(0))
+	case FloatType:
+		itemType = reflect.TypeOf(float64(0))
 	case BoolType:
 		itemType = reflect.TypeOf(false)
 	case StringType:
 		itemType = reflect.TypeOf("")
+	case DurationType:
+		itemType = durationType
 	case SliceType:
 		subItemType, err := typeInfo.ItemType.makeSliceType()
 
@@ -513,6 +1404,8 @@ func (typeInfo ArgumentTypeInfo) makeSliceType() (reflect.Type, error) {
 		}
 
 		itemType = subItemType
+	case AnyType:
+		itemType = interfaceType
 	default:
 		return nil, fmt.Errorf("invalid type: %v", typeInfo.ItemType.ActualType)
 	}
@@ -533,10 +1426,14 @@ func (typeInfo ArgumentTypeInfo) makeMapType() (reflect.Type, error) {
 	switch typeInfo.ItemType.ActualType {
 	case IntegerType:
 		itemType = reflect.TypeOf(int(0))
+	case FloatType:
+		itemType = reflect.TypeOf(float64(0))
 	case BoolType:
 		itemType = reflect.TypeOf(false)
 	case StringType:
 		itemType = reflect.TypeOf("")
+	case DurationType:
+		itemType = durationType
 	case SliceType:
 		subItemType, err := typeInfo.ItemType.makeSliceType()
 
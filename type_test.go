@@ -1,8 +1,11 @@
 package marker
 
 import (
+	"errors"
 	"reflect"
+	"strconv"
 	"testing"
+	"time"
 )
 
 func TestGetArgumentTypeInfo(t *testing.T) {
@@ -118,6 +121,16 @@ func TestGetArgumentTypeInfo(t *testing.T) {
 		{
 			Type: reflect.TypeOf(&struct {
 			}{}),
+			MustHaveError: false,
+			ExpectedType:  NestedStructType,
+		},
+		{
+			Type:          reflect.TypeOf(make(chan int)),
+			MustHaveError: true,
+			ExpectedType:  InvalidType,
+		},
+		{
+			Type:          reflect.TypeOf(testRecursiveRule{}),
 			MustHaveError: true,
 			ExpectedType:  InvalidType,
 		},
@@ -138,3 +151,304 @@ func TestGetArgumentTypeInfo(t *testing.T) {
 		}
 	}
 }
+
+func TestArgumentTypeInfo_ParseSliceWithQuotedElements(t *testing.T) {
+	typeInfo, err := GetArgumentTypeInfo(reflect.TypeOf([]string{}))
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var values []string
+	err = typeInfo.Parse(NewScanner(`{"a}b", "c,d", "{e}", "f\"}g", `+"`h}i`"+`}`), reflect.ValueOf(&values).Elem())
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"a}b", "c,d", "{e}", "f\"}g", "h}i"}
+
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("slice items are not equal to expected, got %q; want %q", values, expected)
+	}
+}
+
+func TestArgumentTypeInfo_ParseSliceSeparators(t *testing.T) {
+	testCases := []struct {
+		Source   string
+		Value    interface{}
+		Expected interface{}
+		Error    string
+	}{
+		{Source: `{a,b}`, Value: &[]string{}, Expected: []string{"a", "b"}},
+		{Source: `{a;b}`, Value: &[]string{}, Expected: []string{"a;b"}},
+		{Source: `{a;b, c}`, Value: &[]string{}, Expected: []string{"a;b", "c"}},
+		{Source: `{"a;b",c}`, Value: &[]string{}, Expected: []string{"a;b", "c"}},
+		{Source: `a;b;c`, Value: &[]string{}, Expected: []string{"a", "b", "c"}},
+		{Source: `a;b,c`, Value: &[]string{}, Expected: []string{"a", "b"}},
+		{Source: `{1,2}`, Value: &[]int{}, Expected: []int{1, 2}},
+		{Source: `1;2`, Value: &[]int{}, Expected: []int{1, 2}},
+		{Source: `{1;2}`, Value: &[]int{}, Error: `got ";"; want Comma ','`},
+		{Source: `1 2`, Value: &[]int{}, Error: `got "2"; want Semicolon ';'`},
+		{Source: `{a;b,c}`, Value: &[][]string{}, Expected: [][]string{{"a", "b"}, {"c"}}},
+		{Source: `{{a;b},{c}}`, Value: &[][]string{}, Expected: [][]string{{"a;b"}, {"c"}}},
+	}
+
+	for _, testCase := range testCases {
+		value := reflect.ValueOf(testCase.Value).Elem()
+		typeInfo, err := GetArgumentTypeInfo(value.Type())
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var messages []string
+		scanner := NewScanner(testCase.Source)
+		scanner.ErrorCallback = func(scanner *Scanner, message string) {
+			messages = append(messages, message)
+		}
+
+		err = typeInfo.Parse(scanner, value)
+
+		if err != nil {
+			messages = append(messages, err.Error())
+		}
+
+		if testCase.Error != "" {
+			if len(messages) != 1 || messages[0] != testCase.Error {
+				t.Errorf("%s: errors are not equal to expected, got %q; want %q", testCase.Source, messages, testCase.Error)
+			}
+
+			continue
+		}
+
+		if len(messages) != 0 {
+			t.Errorf("%s: unexpected errors: %q", testCase.Source, messages)
+		} else if !reflect.DeepEqual(value.Interface(), testCase.Expected) {
+			t.Errorf("%s: slice items are not equal to expected, got %q; want %q", testCase.Source, value.Interface(), testCase.Expected)
+		}
+	}
+}
+
+func TestArgumentTypeInfo_ParseMapWithSliceValues(t *testing.T) {
+	typeInfo, err := GetArgumentTypeInfo(reflect.TypeOf(map[string][]int{}))
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var values map[string][]int
+	err = typeInfo.Parse(NewScanner("{first:{1,2,3},second:{4},third:{}}"), reflect.ValueOf(&values).Elem())
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string][]int{
+		"first":  {1, 2, 3},
+		"second": {4},
+		"third":  nil,
+	}
+
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("map entries are not equal to expected, got %v; want %v", values, expected)
+	}
+
+	values["first"][0] = 10
+
+	if values["second"][0] != 4 {
+		t.Errorf("map values must not share the same slice, got %v", values)
+	}
+}
+
+func TestArgumentTypeInfo_ParseMapWithMultipleKeys(t *testing.T) {
+	typeInfo, err := GetArgumentTypeInfo(reflect.TypeOf(map[string]map[string]string{}))
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var values map[string]map[string]string
+	err = typeInfo.Parse(NewScanner(`{a:{x:"1"},b:{y:"2",z:"3"},c:{}}`), reflect.ValueOf(&values).Elem())
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]map[string]string{
+		"a": {"x": "1"},
+		"b": {"y": "2", "z": "3"},
+		"c": {},
+	}
+
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("map entries are not equal to expected, got %v; want %v", values, expected)
+	}
+
+	values["a"]["x"] = "changed"
+
+	if values["b"]["y"] != "2" || len(values["c"]) != 0 {
+		t.Errorf("map entries must be independent, got %v", values)
+	}
+}
+
+func TestArgumentTypeInfo_ParseDurationSlice(t *testing.T) {
+	typeInfo, err := GetArgumentTypeInfo(reflect.TypeOf([]time.Duration{}))
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if typeInfo.ItemType.ActualType != DurationType {
+		t.Fatalf("item type is not equal to expected, got %v; want %v", typeInfo.ItemType.ActualType, DurationType)
+	}
+
+	var values []time.Duration
+	err = typeInfo.Parse(NewScanner(`{1s, 2m, "1h30m", 500ms}`), reflect.ValueOf(&values).Elem())
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []time.Duration{time.Second, 2 * time.Minute, 90 * time.Minute, 500 * time.Millisecond}
+
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("slice elements are not equal to expected, got %v; want %v", values, expected)
+	}
+
+	err = typeInfo.Parse(NewScanner(`{1s, 2x}`), reflect.ValueOf(&values).Elem())
+
+	if err == nil {
+		t.Errorf("an error is expected for the invalid duration")
+	}
+}
+
+func TestArgumentTypeInfo_ParseDurationMap(t *testing.T) {
+	typeInfo, err := GetArgumentTypeInfo(reflect.TypeOf(map[string]time.Duration{}))
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var values map[string]time.Duration
+	err = typeInfo.Parse(NewScanner(`{read:5s,write:"1m"}`), reflect.ValueOf(&values).Elem())
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]time.Duration{
+		"read":  5 * time.Second,
+		"write": time.Minute,
+	}
+
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("map entries are not equal to expected, got %v; want %v", values, expected)
+	}
+}
+
+func TestArgumentTypeInfo_ParseIntegerWithDigitSeparators(t *testing.T) {
+	testCases := []struct {
+		Source        string
+		Expected      int
+		MustHaveError bool
+	}{
+		{"1_000_000", 1000000, false},
+		{"-1_000", -1000, false},
+		{"12", 12, false},
+		{"1__000", 0, true},
+		{"1000_", 0, true},
+	}
+
+	typeInfo := ArgumentTypeInfo{ActualType: IntegerType}
+
+	for _, testCase := range testCases {
+		var value int
+		err := typeInfo.Parse(NewScanner(testCase.Source), reflect.ValueOf(&value).Elem())
+
+		if testCase.MustHaveError {
+			if err == nil {
+				t.Errorf("an error is expected for %q", testCase.Source)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("unexpected error for %q: %v", testCase.Source, err)
+		}
+
+		if value != testCase.Expected {
+			t.Errorf("value is not equal to expected for %q, got %d; want %d", testCase.Source, value, testCase.Expected)
+		}
+	}
+}
+
+type testRecursiveRule struct {
+	Rules []testRecursiveRule `marker:"rules"`
+}
+
+type testRule struct {
+	Name  string `marker:"name"`
+	Level int    `marker:"level,optional"`
+}
+
+func TestArgumentTypeInfo_ParseNestedStructSlice(t *testing.T) {
+	typeInfo, err := GetArgumentTypeInfo(reflect.TypeOf([]testRule{}))
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var values []testRule
+	err = typeInfo.Parse(NewScanner(`{{name=a,level=1}, {level=2, name="b,c"}, {name=d}}`), reflect.ValueOf(&values).Elem())
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []testRule{
+		{Name: "a", Level: 1},
+		{Name: "b,c", Level: 2},
+		{Name: "d"},
+	}
+
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("slice elements are not equal to expected, got %v; want %v", values, expected)
+	}
+
+	err = typeInfo.Parse(NewScanner(`{{level=1}}`), reflect.ValueOf(&values).Elem())
+
+	if err == nil || err.Error() != "missing argument(s) name of marker.testRule" {
+		t.Errorf("an error is expected for the missing argument, got %v", err)
+	}
+}
+
+func TestArgumentTypeInfo_ParseFloat32Overflow(t *testing.T) {
+	typeInfo, err := GetArgumentTypeInfo(reflect.TypeOf(float32(0)))
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if typeInfo.ActualType != FloatType {
+		t.Fatalf("type is not equal to expected, got %v; want %v", typeInfo.ActualType, FloatType)
+	}
+
+	var value float32
+	err = typeInfo.Parse(NewScanner("3.4e38"), reflect.ValueOf(&value).Elem())
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if value != 3.4e38 {
+		t.Errorf("value is not equal to expected, got %v; want %v", value, float32(3.4e38))
+	}
+
+	err = typeInfo.Parse(NewScanner("1e39"), reflect.ValueOf(&value).Elem())
+
+	if !errors.Is(err, strconv.ErrRange) {
+		t.Errorf("a range error is expected for the overflow, got %v", err)
+	}
+}
@@ -62,3 +62,120 @@ func GoModDir() (string, error) {
 
 	return pkg.Module.Dir, nil
 }
+
+// expandVariables replaces the variable references such as $NAME and ${NAME} in the given
+// marker arguments with the values of the variables. A default value can be given as
+// ${NAME:-fallback} for a variable which does not exist or is empty, and '$$' is replaced
+// with a single '$'. The quoted strings such as "$HOME" are kept as they are.
+func expandVariables(text string, variables map[string]string) (string, error) {
+	var builder strings.Builder
+
+	for index := 0; index < len(text); index++ {
+		if quote := text[index]; quote == '"' || quote == '`' {
+			end := index + 1
+
+			for end < len(text) && text[end] != quote {
+				// the escaped quotes do not close the interpreted strings
+				if quote == '"' && text[end] == '\\' {
+					end++
+				}
+
+				end++
+			}
+
+			if end >= len(text) {
+				end = len(text) - 1
+			}
+
+			builder.WriteString(text[index : end+1])
+			index = end
+			continue
+		}
+
+		if text[index] != '$' || index+1 == len(text) {
+			builder.WriteByte(text[index])
+			continue
+		}
+
+		next := text[index+1]
+
+		if next == '$' {
+			builder.WriteByte('$')
+			index++
+			continue
+		}
+
+		if next == '{' {
+			end := strings.IndexByte(text[index+2:], '}')
+
+			if end == -1 {
+				return "", fmt.Errorf("variable reference %q is not closed", text[index:])
+			}
+
+			reference := text[index+2 : index+2+end]
+			name := reference
+			defaultValue, hasDefault := "", false
+
+			if separatorIndex := strings.Index(reference, ":-"); separatorIndex != -1 {
+				name = reference[:separatorIndex]
+				defaultValue, hasDefault = reference[separatorIndex+2:], true
+			}
+
+			if !isVariableName(name) {
+				return "", fmt.Errorf("variable name %q is not valid", name)
+			}
+
+			value, ok := variables[name]
+
+			if !ok && !hasDefault {
+				return "", fmt.Errorf("variable %q cannot be resolved", name)
+			} else if hasDefault && value == "" {
+				value = defaultValue
+			}
+
+			builder.WriteString(value)
+			index += end + 2
+			continue
+		}
+
+		length := 0
+
+		for index+1+length < len(text) && IsIdentifier(rune(text[index+1+length]), length) {
+			length++
+		}
+
+		// a dollar sign which is not followed by a variable name is kept as it is
+		if length == 0 {
+			builder.WriteByte('$')
+			continue
+		}
+
+		name := text[index+1 : index+1+length]
+		value, ok := variables[name]
+
+		if !ok {
+			return "", fmt.Errorf("variable %q cannot be resolved", name)
+		}
+
+		builder.WriteString(value)
+		index += length
+	}
+
+	return builder.String(), nil
+}
+
+// isVariableName checks if the given name consists of letters, digits and underscores
+// and does not start with a digit.
+func isVariableName(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for index, character := range name {
+		if !IsIdentifier(character, index) {
+			return false
+		}
+	}
+
+	return true
+}
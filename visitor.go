@@ -12,23 +12,43 @@ type commentVisitor struct {
 	packageMarkers     []markerComment
 	importMarkers      []markerComment
 	declarationMarkers []markerComment
-	nodeMarkers        map[ast.Node][]markerComment
+	// variableMarkers are the markers of a single variable declaration, they are
+	// passed to its value specification.
+	variableMarkers []markerComment
+	// functionLiteralMarkers are the markers of the last statement or value specification,
+	// they are associated with the next function literal in it.
+	functionLiteralMarkers []markerComment
+	nodeMarkers            map[ast.Node][]markerComment
+	// ignoredComments are the comments which must not be associated with the next node
+	// such as the trailing comments of the fields and the comments before a closing brace.
+	ignoredComments map[*ast.CommentGroup]bool
+	// ancestors are the nodes whose children are being visited.
+	ancestors []ast.Node
+	// parents are the enclosing declarations of the nodes which can carry markers.
+	parents map[ast.Node]ast.Node
 }
 
 func newCommentVisitor(allComments []*ast.CommentGroup) *commentVisitor {
 	return &commentVisitor{
-		allComments: allComments,
-		nodeMarkers: make(map[ast.Node][]markerComment),
+		allComments:     allComments,
+		nodeMarkers:     make(map[ast.Node][]markerComment),
+		ignoredComments: make(map[*ast.CommentGroup]bool),
+		parents:         make(map[ast.Node]ast.Node),
 	}
 }
 
 func (visitor *commentVisitor) Visit(node ast.Node) (w ast.Visitor) {
 
 	if node == nil {
+		// all the children of the last ancestor have been visited
+		if len(visitor.ancestors) != 0 {
+			visitor.ancestors = visitor.ancestors[:len(visitor.ancestors)-1]
+		}
+
 		return nil
 	}
 
-	switch node.(type) {
+	switch typedNode := node.(type) {
 	case *ast.CommentGroup:
 		return nil
 	case *ast.Ident:
@@ -36,8 +56,11 @@ func (visitor *commentVisitor) Visit(node ast.Node) (w ast.Visitor) {
 	case *ast.ImportSpec:
 		return nil
 	case *ast.FieldList:
+		visitor.ignoreClosingComments(typedNode)
+		visitor.ancestors = append(visitor.ancestors, node)
 		return visitor
 	case *ast.InterfaceType:
+		visitor.ancestors = append(visitor.ancestors, node)
 		return visitor
 	case *ast.FuncType:
 		return nil
@@ -91,20 +114,135 @@ func (visitor *commentVisitor) Visit(node ast.Node) (w ast.Visitor) {
 		if typedNode.Tok == token.IMPORT {
 			visitor.nodeMarkers[node] = append(visitor.nodeMarkers[node], markersFromComment...)
 			visitor.nodeMarkers[node] = append(visitor.nodeMarkers[node], markersFromDocument...)
+		} else if typedNode.Tok == token.VAR || typedNode.Tok == token.CONST {
+			visitor.variableMarkers = nil
+
+			if !typedNode.Lparen.IsValid() {
+				visitor.variableMarkers = append(visitor.variableMarkers, markersFromComment...)
+				visitor.variableMarkers = append(visitor.variableMarkers, markersFromDocument...)
+			}
 		} else {
 			visitor.declarationMarkers = append(visitor.declarationMarkers, markersFromComment...)
 			visitor.declarationMarkers = append(visitor.declarationMarkers, markersFromDocument...)
 		}
-	case *ast.Field, *ast.FuncDecl:
+	case *ast.Field:
+		visitor.nodeMarkers[node] = append(visitor.nodeMarkers[node], markersFromComment...)
+		visitor.nodeMarkers[node] = append(visitor.nodeMarkers[node], markersFromDocument...)
+
+		// the trailing comment such as 'Name string // +marker' belongs to the field
+		if typedNode.Comment != nil {
+			visitor.nodeMarkers[node] = append(visitor.nodeMarkers[node], visitor.getCommentGroupMarkers(typedNode.Comment)...)
+			visitor.ignoredComments[typedNode.Comment] = true
+		}
+	case *ast.FuncDecl:
+		visitor.nodeMarkers[node] = append(visitor.nodeMarkers[node], markersFromComment...)
+		visitor.nodeMarkers[node] = append(visitor.nodeMarkers[node], markersFromDocument...)
+	case *ast.ValueSpec:
+		specMarkers := append(visitor.variableMarkers, markersFromComment...)
+		specMarkers = append(specMarkers, markersFromDocument...)
+		visitor.variableMarkers = nil
+
+		// the trailing comment such as 'Max = 10 // +marker' belongs to the specification,
+		// it must not be associated with the next specification in the same block
+		if typedNode.Comment != nil {
+			specMarkers = append(specMarkers, visitor.getCommentGroupMarkers(typedNode.Comment)...)
+			visitor.ignoredComments[typedNode.Comment] = true
+		}
+
+		// the markers of a specification such as 'var handler = func() {}' belong to
+		// the function literal in it, they are not associated with the specification
+		if containsFunctionLiteral(typedNode) {
+			visitor.functionLiteralMarkers = specMarkers
+		} else {
+			visitor.nodeMarkers[node] = append(visitor.nodeMarkers[node], specMarkers...)
+			visitor.functionLiteralMarkers = nil
+		}
+	case ast.Stmt:
+		visitor.functionLiteralMarkers = nil
+		visitor.functionLiteralMarkers = append(visitor.functionLiteralMarkers, markersFromComment...)
+		visitor.functionLiteralMarkers = append(visitor.functionLiteralMarkers, markersFromDocument...)
+	case *ast.FuncLit:
+		visitor.nodeMarkers[node] = append(visitor.nodeMarkers[node], visitor.functionLiteralMarkers...)
 		visitor.nodeMarkers[node] = append(visitor.nodeMarkers[node], markersFromComment...)
 		visitor.nodeMarkers[node] = append(visitor.nodeMarkers[node], markersFromDocument...)
+		visitor.functionLiteralMarkers = nil
 	}
 
 	visitor.nextCommentIndex = lastCommentIndex + 1
 
+	switch node.(type) {
+	case *ast.TypeSpec, *ast.GenDecl, *ast.Field, *ast.FuncDecl, *ast.FuncLit, *ast.ValueSpec:
+		visitor.parents[node] = visitor.enclosingDeclaration()
+	}
+
+	visitor.ancestors = append(visitor.ancestors, node)
 	return visitor
 }
 
+// enclosingDeclaration returns the nearest ancestor which is a file, a declaration,
+// a type specification or a function literal.
+func (visitor *commentVisitor) enclosingDeclaration() ast.Node {
+	for index := len(visitor.ancestors) - 1; index >= 0; index-- {
+		switch ancestor := visitor.ancestors[index].(type) {
+		case *ast.File, *ast.GenDecl, *ast.TypeSpec, *ast.FuncDecl, *ast.FuncLit:
+			return ancestor
+		}
+	}
+
+	return nil
+}
+
+// containsFunctionLiteral checks if any value of the given specification contains a function literal.
+func containsFunctionLiteral(valueSpec *ast.ValueSpec) bool {
+	found := false
+
+	for _, value := range valueSpec.Values {
+		ast.Inspect(value, func(node ast.Node) bool {
+			if _, ok := node.(*ast.FuncLit); ok {
+				found = true
+			}
+
+			return !found
+		})
+	}
+
+	return found
+}
+
+// ignoreClosingComments ignores the comments between the last field of the given
+// field list and its closing brace, so that they are not associated with the next node.
+func (visitor *commentVisitor) ignoreClosingComments(fieldList *ast.FieldList) {
+	if !fieldList.Closing.IsValid() {
+		return
+	}
+
+	startPosition := fieldList.Opening
+	var lastFieldComment *ast.CommentGroup
+
+	if len(fieldList.List) != 0 {
+		lastField := fieldList.List[len(fieldList.List)-1]
+		startPosition = lastField.End()
+		lastFieldComment = lastField.Comment
+	}
+
+	for _, commentGroup := range visitor.allComments {
+		if commentGroup != lastFieldComment && commentGroup.Pos() >= startPosition && commentGroup.End() <= fieldList.Closing {
+			visitor.ignoredComments[commentGroup] = true
+		}
+	}
+}
+
+// getCommentGroupMarkers returns the markers in the given comment group.
+func (visitor *commentVisitor) getCommentGroupMarkers(commentGroup *ast.CommentGroup) []markerComment {
+	for index, group := range visitor.allComments {
+		if group == commentGroup {
+			return visitor.getMarkerComments(index, index+1)
+		}
+	}
+
+	return nil
+}
+
 func (visitor *commentVisitor) getMarkerComments(startIndex, endIndex int) []markerComment {
 	if startIndex < 0 || endIndex < 0 {
 		return nil
@@ -115,26 +253,33 @@ func (visitor *commentVisitor) getMarkerComments(startIndex, endIndex int) []mar
 	for index := startIndex; index < endIndex; index++ {
 		commentGroup := visitor.allComments[index]
 
+		if visitor.ignoredComments[commentGroup] {
+			continue
+		}
+
 		var markerComment *markerComment
 		var hasContinuation bool
+		var hasOpenRawString bool
 
-		for _, comment := range commentGroup.List {
+		for _, comment := range getLineComments(commentGroup) {
 			containsMarker := isMarkerComment(comment.Text)
 
-			if containsMarker {
+			if containsMarker && !hasOpenRawString {
 				if markerComment != nil {
 					markerComments = append(markerComments, *markerComment)
 				}
 
 				markerComment = newMarkerComment(comment)
 				hasContinuation = false
-			} else if hasContinuation {
+			} else if hasContinuation || hasOpenRawString {
 				if markerComment != nil {
 					markerComment.append(comment)
 				}
 			}
 
 			hasContinuation = hasContinuationCharacter(comment.Text)
+			// a raw string argument can span multiple comment lines
+			hasOpenRawString = markerComment != nil && isRawStringOpen(markerComment.Text())
 		}
 
 		if markerComment != nil {
@@ -145,6 +290,18 @@ func (visitor *commentVisitor) getMarkerComments(startIndex, endIndex int) []mar
 	return markerComments
 }
 
+// getLineComments returns the comments of the given comment group by splitting
+// the multiline block comments into lines.
+func getLineComments(commentGroup *ast.CommentGroup) []*ast.Comment {
+	lineComments := make([]*ast.Comment, 0, len(commentGroup.List))
+
+	for _, comment := range commentGroup.List {
+		lineComments = append(lineComments, splitBlockComment(comment)...)
+	}
+
+	return lineComments
+}
+
 func (visitor *commentVisitor) getCommentsForNode(node ast.Node) (docCommentGroup *ast.CommentGroup) {
 
 	switch typedNode := node.(type) {
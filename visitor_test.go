@@ -0,0 +1,67 @@
+package marker
+
+import (
+	"github.com/stretchr/testify/assert"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+func TestCommentVisitor_LastFieldMarkers(t *testing.T) {
+	fileSet := token.NewFileSet()
+	file, err := parser.ParseFile(fileSet, "test.go", `package test
+
+type First struct {
+	// +marker:first
+	Name string
+	// +marker:last
+	Surname string
+}
+
+type Second struct {
+	Name    string // +marker:first
+	Surname string // +marker:last
+}
+
+type Third struct {
+	Name string
+	// +marker:dangling
+}
+
+// +marker:type
+type Fourth struct {
+}
+`, parser.ParseComments)
+	assert.Nil(t, err)
+
+	visitor := newCommentVisitor(file.Comments)
+	ast.Walk(visitor, file)
+
+	markers := make(map[string][]string)
+
+	for node, markerComments := range visitor.nodeMarkers {
+		var name string
+
+		switch typedNode := node.(type) {
+		case *ast.Field:
+			name = fileSet.Position(typedNode.Pos()).String() + " " + typedNode.Names[0].Name
+		case *ast.TypeSpec:
+			name = typedNode.Name.Name
+		default:
+			continue
+		}
+
+		for _, markerComment := range markerComments {
+			markers[name] = append(markers[name], markerComment.Text())
+		}
+	}
+
+	assert.Equal(t, map[string][]string{
+		"test.go:5:2 Name":     {"+marker:first"},
+		"test.go:7:2 Surname":  {"+marker:last"},
+		"test.go:11:2 Name":    {"+marker:first"},
+		"test.go:12:2 Surname": {"+marker:last"},
+		"Fourth":               {"+marker:type"},
+	}, markers)
+}
@@ -0,0 +1,240 @@
+package marker
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MarshalYAML renders the given parsed marker value as YAML. The fields of the marker
+// are written with their argument names, the slices and the maps are written as YAML
+// sequences and mappings, and the optional arguments which are not set are omitted.
+func MarshalYAML(value interface{}) ([]byte, error) {
+	lines, err := yamlLines(reflect.ValueOf(value))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(strings.Join(lines, "\n") + "\n"), nil
+}
+
+// yamlLines returns the lines of the given value without any indentation.
+func yamlLines(value reflect.Value) ([]string, error) {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return []string{"null"}, nil
+		}
+
+		value = value.Elem()
+	}
+
+	if !value.IsValid() {
+		return []string{"null"}, nil
+	}
+
+	if value.Type() == durationType {
+		return []string{yamlString(time.Duration(value.Int()).String())}, nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		return []string{yamlString(value.String())}, nil
+	case reflect.Bool:
+		return []string{strconv.FormatBool(value.Bool())}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return []string{strconv.FormatInt(value.Int(), 10)}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return []string{strconv.FormatUint(value.Uint(), 10)}, nil
+	case reflect.Float32, reflect.Float64:
+		return []string{strconv.FormatFloat(value.Float(), 'g', -1, value.Type().Bits())}, nil
+	case reflect.Slice, reflect.Array:
+		return yamlSequenceLines(value)
+	case reflect.Map:
+		return yamlMappingLines(value)
+	case reflect.Struct:
+		return yamlStructLines(value)
+	}
+
+	return nil, fmt.Errorf("value of kind %s cannot be rendered as YAML", value.Kind())
+}
+
+// yamlSequenceLines returns the lines of the given slice as a YAML sequence.
+func yamlSequenceLines(value reflect.Value) ([]string, error) {
+	if value.Len() == 0 {
+		return []string{"[]"}, nil
+	}
+
+	lines := make([]string, 0, value.Len())
+
+	for index := 0; index < value.Len(); index++ {
+		itemLines, err := yamlLines(value.Index(index))
+
+		if err != nil {
+			return nil, err
+		}
+
+		for lineIndex, line := range itemLines {
+			if lineIndex == 0 {
+				lines = append(lines, "- "+line)
+			} else {
+				lines = append(lines, "  "+line)
+			}
+		}
+	}
+
+	return lines, nil
+}
+
+// yamlMappingLines returns the lines of the given map as a YAML mapping ordered by the keys.
+func yamlMappingLines(value reflect.Value) ([]string, error) {
+	if value.Len() == 0 {
+		return []string{"{}"}, nil
+	}
+
+	keys := value.MapKeys()
+
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+	})
+
+	lines := make([]string, 0, len(keys))
+
+	for _, key := range keys {
+		keyLines, err := yamlLines(key)
+
+		if err == nil && (len(keyLines) != 1 || isYAMLCollection(key)) {
+			err = fmt.Errorf("map key of type %s cannot be rendered as YAML", key.Type())
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		entryLines, err := yamlEntryLines(keyLines[0], value.MapIndex(key))
+
+		if err != nil {
+			return nil, err
+		}
+
+		lines = append(lines, entryLines...)
+	}
+
+	return lines, nil
+}
+
+// yamlStructLines returns the lines of the given struct as a YAML mapping whose keys are
+// the argument names of the fields in their declaration order.
+func yamlStructLines(value reflect.Value) ([]string, error) {
+	lines := make([]string, 0, value.NumField())
+
+	for index := 0; index < value.NumField(); index++ {
+		field := value.Type().Field(index)
+
+		if field.PkgPath != "" {
+			continue
+		}
+
+		fieldValue := value.Field(index)
+
+		// the optional arguments which are not set are omitted
+		if fieldValue.Kind() == reflect.Ptr && fieldValue.IsNil() {
+			continue
+		}
+
+		name := LowerCamelCase(field.Name)
+
+		if argument, err := ExtractArgument(field); err == nil {
+			name = argument.Name
+		}
+
+		entryLines, err := yamlEntryLines(yamlString(name), fieldValue)
+
+		if err != nil {
+			return nil, fmt.Errorf("field %s cannot be rendered as YAML : %w", field.Name, err)
+		}
+
+		lines = append(lines, entryLines...)
+	}
+
+	if len(lines) == 0 {
+		return []string{"{}"}, nil
+	}
+
+	return lines, nil
+}
+
+// yamlEntryLines returns the lines of a mapping entry with the given YAML key and value.
+// The scalar values are written in the same line as the key, and the others are
+// written in the following lines with indentation.
+func yamlEntryLines(key string, value reflect.Value) ([]string, error) {
+	valueLines, err := yamlLines(value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// the empty collections are written as [] and {}
+	if len(valueLines) == 1 && (!isYAMLCollection(value) || valueLines[0] == "[]" || valueLines[0] == "{}") {
+		return []string{key + ": " + valueLines[0]}, nil
+	}
+
+	lines := make([]string, 0, len(valueLines)+1)
+	lines = append(lines, key+":")
+
+	for _, line := range valueLines {
+		lines = append(lines, "  "+line)
+	}
+
+	return lines, nil
+}
+
+// isYAMLCollection checks if the given value is rendered as a sequence or a mapping.
+func isYAMLCollection(value reflect.Value) bool {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return false
+		}
+
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct:
+		return true
+	}
+
+	return false
+}
+
+// yamlString returns the given string as a plain YAML scalar if it cannot be mistaken
+// for another type, otherwise it returns the string in double quotes.
+func yamlString(text string) string {
+	if text == "" {
+		return `""`
+	}
+
+	switch strings.ToLower(text) {
+	case "true", "false", "yes", "no", "on", "off", "null", "~":
+		return strconv.Quote(text)
+	}
+
+	if _, err := strconv.ParseFloat(text, 64); err == nil {
+		return strconv.Quote(text)
+	}
+
+	for index, character := range text {
+		isPlain := character == '_' || character == '.' || character == '/' ||
+			'a' <= character && character <= 'z' || 'A' <= character && character <= 'Z' || IsDecimal(character) ||
+			index != 0 && (character == '-' || character == ' ' && index != len(text)-1)
+
+		if !isPlain {
+			return strconv.Quote(text)
+		}
+	}
+
+	return text
+}
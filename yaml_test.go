@@ -0,0 +1,89 @@
+package marker
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type testYAMLRoute struct {
+	Path    string `marker:"path"`
+	Methods []string
+}
+
+type testYAMLMarker struct {
+	Name     string            `marker:"name"`
+	Replicas int               `marker:"replicas"`
+	Enabled  bool              `marker:"enabled"`
+	Ratio    float64           `marker:"ratio"`
+	Timeout  time.Duration     `marker:"timeout"`
+	Tags     []string          `marker:"tags"`
+	Labels   map[string]string `marker:"labels"`
+	Routes   []testYAMLRoute   `marker:"routes"`
+	Owner    *string           `marker:"owner,optional"`
+	Empty    []int             `marker:"empty,optional"`
+}
+
+func TestMarshalYAML(t *testing.T) {
+	value := testYAMLMarker{
+		Name:     "api server",
+		Replicas: 3,
+		Enabled:  true,
+		Ratio:    0.5,
+		Timeout:  90 * time.Second,
+		Tags:     []string{"web", "true", "1.0"},
+		Labels:   map[string]string{"tier": "backend", "app": "api: v1"},
+		Routes: []testYAMLRoute{
+			{Path: "/books", Methods: []string{"GET", "POST"}},
+		},
+	}
+
+	data, err := MarshalYAML(value)
+	assert.Nil(t, err)
+	assert.Equal(t, `name: api server
+replicas: 3
+enabled: true
+ratio: 0.5
+timeout: 1m30s
+tags:
+  - web
+  - "true"
+  - "1.0"
+labels:
+  app: "api: v1"
+  tier: backend
+routes:
+  - path: /books
+    methods:
+      - GET
+      - POST
+empty: []
+`, string(data))
+
+	data, err = MarshalYAML("value")
+	assert.Nil(t, err)
+	assert.Equal(t, "value\n", string(data))
+
+	_, err = MarshalYAML(struct {
+		Channel chan int
+	}{})
+	assert.NotNil(t, err)
+	assert.Equal(t, "field Channel cannot be rendered as YAML : value of kind chan cannot be rendered as YAML", err.Error())
+}
+
+func TestMarshalYAML_NonStringKeys(t *testing.T) {
+	data, err := MarshalYAML(map[int]string{2: "two", 10: "ten", 1: "one"})
+	assert.Nil(t, err)
+	assert.Equal(t, `1: one
+10: ten
+2: two
+`, string(data))
+
+	data, err = MarshalYAML(map[bool]int{true: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, "true: 1\n", string(data))
+
+	_, err = MarshalYAML(map[[2]int]string{{1, 2}: "pair"})
+	assert.NotNil(t, err)
+	assert.Equal(t, "map key of type [2]int cannot be rendered as YAML", err.Error())
+}